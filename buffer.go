@@ -23,6 +23,8 @@
 package errors
 
 import (
+	"strconv"
+	"sync"
 	"unicode/utf8"
 	"unsafe"
 )
@@ -55,6 +57,27 @@ func NewWriteBuffer(n int) (buf *writeBuffer) { //nolint:bgolint
 	}
 }
 
+const maxPooledBuffer = 64 << 10 // 超过此容量的 writeBuffer 不放回 pool, 避免长期占用大块内存
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &writeBuffer{buf: make([]byte, 0, 1024)}
+	},
+}
+
+// getBuffer 从 pool 中取出一个空的 writeBuffer, 用完后需调用 putBuffer 归还
+func getBuffer() *writeBuffer {
+	return bufferPool.Get().(*writeBuffer)
+}
+
+func putBuffer(buf *writeBuffer) {
+	if cap(buf.buf) > maxPooledBuffer {
+		return
+	}
+	buf.buf = buf.buf[:0]
+	bufferPool.Put(buf)
+}
+
 func (buf *writeBuffer) Bytes() []byte { return buf.buf }
 
 func (buf *writeBuffer) String() string {
@@ -82,6 +105,20 @@ func (buf *writeBuffer) WriteString(s string) {
 func (buf *writeBuffer) WriteByte(c byte) {
 	buf.buf = append(buf.buf, c)
 }
+func (buf *writeBuffer) WriteInt(i int) {
+	buf.buf = strconv.AppendInt(buf.buf, int64(i), 10)
+}
+
+// intLen 返回 i 的十进制字符串长度
+func intLen(i int) (l int) {
+	if i <= 0 {
+		l, i = 1, -i
+	}
+	for ; i > 0; i /= 10 {
+		l++
+	}
+	return
+}
 
 // appendEscape 将 src 以 JSON 字符串转义规则追加到 bs 后返回。语义与 writeBuffer.WriteEscape 一致。
 func appendEscape(bs []byte, src string) []byte {
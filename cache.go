@@ -37,15 +37,84 @@ type RCUCache[K comparable, V any] struct {
 
 	cache unsafe.Pointer
 	New   func(K) V
+
+	// 有界模式, 见 SetLimit
+	limit    atomic.Int64
+	evict    atomic.Bool
+	mu       sync.Mutex
+	overflow map[K]V // 尚未合并到 cache 的新 key, 由 mu 保护
+
+	counter cacheCounter
+}
+
+// SetLimit 设置最多缓存 maxEntries 个 key, maxEntries <= 0 时恢复为不限数量。
+// 有界模式下新 key 先写入加锁的 overflow, 积累一批后再整体合并到只读 map, 插入的均摊开销为 O(1),
+// 命中只读 map 的读取仍然无锁; 数量达到上限后, evict 为 true 时随机淘汰一批旧 key, 否则不再缓存新 key
+func (c *RCUCache[K, V]) SetLimit(maxEntries int, evict bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict.Store(evict)
+	c.limit.Store(int64(maxEntries))
+	if maxEntries <= 0 && len(c.overflow) > 0 {
+		// 恢复为不限数量后不再读取 overflow, 需要先合并
+		var cache map[K]V
+		if p := atomic.LoadPointer(&c.cache); p != nil {
+			cache = *(*map[K]V)(p)
+		}
+		c.promote(cache, len(cache)+len(c.overflow))
+	}
+}
+
+// SetCacheLimit 给本包内部按调用栈缓存解析结果的 cache 设置上限, 参数同 RCUCache.SetLimit;
+// 适用于插件、代码生成等会产生大量不同调用栈的长期运行服务
+func SetCacheLimit(maxEntries int, evict bool) {
+	cacheStack.SetLimit(maxEntries, evict)
+	cacheCallers.SetLimit(maxEntries, evict)
+	cacheCaller.SetLimit(maxEntries, evict)
+	cacheWrapper.SetLimit(maxEntries, evict)
+	cacheSite.SetLimit(maxEntries, evict)
+	siteCounters.SetLimit(maxEntries, evict)
+}
+
+// Reset 清空所有缓存的 key 和统计计数, SetLimit 的设置保持不变
+func (c *RCUCache[K, V]) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.overflow = nil
+	atomic.StorePointer(&c.cache, nil)
+	c.counter.reset()
+}
+
+// Len 返回缓存的 key 数量
+func (c *RCUCache[K, V]) Len() (n int) {
+	if p := atomic.LoadPointer(&c.cache); p != nil {
+		n = len(*(*map[K]V)(p))
+	}
+	c.mu.Lock()
+	n += len(c.overflow)
+	c.mu.Unlock()
+	return
 }
 
 func (c *RCUCache[K, V]) JustGet(key K) (v V, ok bool) {
+	v, ok = c.lookup(key)
+	c.counter.count(ok)
+	return
+}
+
+// lookup 同 JustGet, 但不计入统计
+func (c *RCUCache[K, V]) lookup(key K) (v V, ok bool) {
 	var cache map[K]V
 
 	if p := atomic.LoadPointer(&c.cache); p != nil {
 		cache = *(*map[K]V)(p)
 		v, ok = cache[key]
 	}
+	if !ok && c.limit.Load() > 0 {
+		c.mu.Lock()
+		v, ok = c.overflow[key]
+		c.mu.Unlock()
+	}
 
 	return
 }
@@ -58,9 +127,23 @@ func (c *RCUCache[K, V]) Get(key K) (v V) {
 		cache = *(*map[K]V)(p)
 		v, ok = cache[key]
 		if ok {
+			c.counter.hit()
 			return v
 		}
 	}
+	if limit := c.limit.Load(); limit > 0 {
+		c.mu.Lock()
+		v, ok = c.overflow[key]
+		c.mu.Unlock()
+		c.counter.count(ok)
+		if ok || c.New == nil {
+			return
+		}
+		v = c.New(key)
+		c.store(key, v, int(limit))
+		return
+	}
+	c.counter.misses.Add(1)
 	if c.New == nil {
 		return
 	}
@@ -80,11 +163,16 @@ func (c *RCUCache[K, V]) Get(key K) (v V) {
 			break
 		}
 	}
+	c.counter.copies.Add(1)
 
 	return v
 }
 
 func (c *RCUCache[K, V]) Set(key K, value V) {
+	if limit := c.limit.Load(); limit > 0 {
+		c.store(key, value, int(limit))
+		return
+	}
 	var cache map[K]V
 
 	p := atomic.LoadPointer(&c.cache)
@@ -93,11 +181,11 @@ func (c *RCUCache[K, V]) Set(key K, value V) {
 	}
 
 	cacheNew := make(map[K]V, len(cache)+8)
-	cacheNew[key] = value
 	for {
 		for k, v := range cache {
 			cacheNew[k] = v
 		}
+		cacheNew[key] = value // 覆盖已有的 key
 		swapped := atomic.CompareAndSwapPointer(&c.cache, p, unsafe.Pointer(&cacheNew))
 		if swapped {
 			break
@@ -107,6 +195,100 @@ func (c *RCUCache[K, V]) Set(key K, value V) {
 			cache = *(*map[K]V)(p)
 		}
 	}
+	c.counter.copies.Add(1)
+}
+
+// merge 批量写入 m 中尚未缓存的 key, 返回写入的数量; 不限数量时只复制一次只读 map, 有界模式下逐个 store
+func (c *RCUCache[K, V]) merge(m map[K]V) (n int) {
+	if limit := c.limit.Load(); limit > 0 {
+		for k, v := range m {
+			if _, ok := c.lookup(k); !ok && c.store(k, v, int(limit)) {
+				n++
+			}
+		}
+		return
+	}
+	for {
+		var cache map[K]V
+		p := atomic.LoadPointer(&c.cache)
+		if p != nil {
+			cache = *(*map[K]V)(p)
+		}
+		cacheNew := make(map[K]V, len(cache)+len(m))
+		for k, v := range cache {
+			cacheNew[k] = v
+		}
+		n = 0
+		for k, v := range m {
+			if _, ok := cacheNew[k]; !ok {
+				cacheNew[k] = v
+				n++
+			}
+		}
+		if atomic.CompareAndSwapPointer(&c.cache, p, unsafe.Pointer(&cacheNew)) {
+			break
+		}
+	}
+	c.counter.copies.Add(1)
+	return
+}
+
+// store 有界模式下写入 key; 数量达到上限且不淘汰时返回 false
+func (c *RCUCache[K, V]) store(key K, value V, limit int) (stored bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var cache map[K]V
+	if p := atomic.LoadPointer(&c.cache); p != nil {
+		cache = *(*map[K]V)(p)
+	}
+	_, exist := cache[key]
+	if _, ok := c.overflow[key]; !ok && !exist && len(cache)+len(c.overflow) >= limit && !c.evict.Load() {
+		c.counter.dropped.Add(1)
+		return false
+	}
+	if c.overflow == nil {
+		c.overflow = make(map[K]V)
+	}
+	c.overflow[key] = value
+	if len(c.overflow) >= len(cache)/4+16 || len(cache)+len(c.overflow) >= limit {
+		c.promote(cache, limit)
+	}
+	return true
+}
+
+// promote 把 overflow 合并到只读 map; 超过 limit 时淘汰到 limit 的 3/4, 使得之后的合并开销可以被均摊;
+// store 保证 overflow 不超过 limit, overflow 中的 key 全部保留
+func (c *RCUCache[K, V]) promote(cache map[K]V, limit int) {
+	n := len(cache)
+	for k := range c.overflow {
+		if _, ok := cache[k]; !ok {
+			n++
+		}
+	}
+	keep := len(cache)
+	if n > limit {
+		keep = limit - limit/4 - len(c.overflow)
+	}
+	cacheNew := make(map[K]V, max(keep, 0)+len(c.overflow))
+	for k, v := range c.overflow {
+		cacheNew[k] = v
+	}
+	for k, v := range cache {
+		if keep <= 0 {
+			break
+		}
+		if _, ok := cacheNew[k]; !ok {
+			cacheNew[k] = v
+			keep--
+		}
+	}
+	atomic.StorePointer(&c.cache, unsafe.Pointer(&cacheNew))
+	c.counter.copies.Add(1)
+	if n > len(cacheNew) {
+		c.counter.evicted.Add(uint64(n - len(cacheNew)))
+	}
+	c.overflow = nil
 }
 
 type StackCache[V any] struct {
@@ -114,15 +296,26 @@ type StackCache[V any] struct {
 }
 
 func (c *StackCache[V]) Get(s *[DefaultDepth]uintptr, l int) (v V) {
-	v, ok := c.JustGet(stackToStr(s, l))
+	return c.GetPCs(s[:l])
+}
+
+func (c *StackCache[V]) Set(s *[DefaultDepth]uintptr, l int, v V) {
+	c.SetPCs(s[:l], v)
+}
+
+// GetPCs 同 Get, 但 pcs 可以是任意深度
+func (c *StackCache[V]) GetPCs(pcs []uintptr) (v V) {
+	v, ok := c.lookup(pcsToStr(pcs))
 	if ok || c.New == nil {
+		c.counter.count(ok)
 		return
 	}
-	return c.RCUCache.Get(stackToNewStr(s, l))
+	return c.RCUCache.Get(pcsToNewStr(pcs))
 }
 
-func (c *StackCache[V]) Set(s *[DefaultDepth]uintptr, l int, v V) {
-	c.RCUCache.Set(stackToNewStr(s, l), v)
+// SetPCs 同 Set, 但 pcs 可以是任意深度
+func (c *StackCache[V]) SetPCs(pcs []uintptr, v V) {
+	c.RCUCache.Set(pcsToNewStr(pcs), v)
 }
 
 type stackCache1[V any] struct {
@@ -165,3 +358,12 @@ func stackToStr(s *[DefaultDepth]uintptr, l int) string {
 	p := (*byte)(unsafe.Pointer(s))
 	return unsafe.String(p, l*8)
 }
+
+func pcsToNewStr(pcs []uintptr) string {
+	return string(unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(pcs))), len(pcs)*int(unsafe.Sizeof(uintptr(0)))))
+}
+
+// pcsToStr 返回的 string 与 pcs 共用内存, 只能用于查询
+func pcsToStr(pcs []uintptr) string {
+	return unsafe.String((*byte)(unsafe.Pointer(unsafe.SliceData(pcs))), len(pcs)*int(unsafe.Sizeof(uintptr(0))))
+}
@@ -49,6 +49,109 @@ func TestCache(t *testing.T) {
 			assert.Equal(t, v, i*100)
 		}
 	})
+
+	t.Run("RCUCache-Update", func(t *testing.T) {
+		cache := RCUCache[int, int]{}
+		cache.Set(1, 1)
+		cache.Set(2, 2)
+		cache.Set(1, 10)
+		v, ok := cache.JustGet(1)
+		assert.True(t, ok)
+		assert.Equal(t, 10, v)
+		assert.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("RCUCache-Reset", func(t *testing.T) {
+		cache := RCUCache[int, int]{New: func(i int) int { return i }}
+		cache.SetLimit(4, false)
+		for i := 0; i < 8; i++ {
+			cache.Get(i)
+		}
+		assert.Equal(t, 4, cache.Len())
+		cache.Reset()
+		assert.Equal(t, 0, cache.Len())
+		assert.Equal(t, CacheStat{Name: "reset"}, cache.Stat("reset"))
+		for i := 0; i < 8; i++ {
+			cache.Get(i)
+		}
+		assert.Equal(t, 4, cache.Len(), "limit kept")
+	})
+
+	t.Run("RCUCache-Merge", func(t *testing.T) {
+		m := map[int]int{}
+		for i := 0; i < 100; i++ {
+			m[i] = i
+		}
+		cache := RCUCache[int, int]{}
+		cache.Set(1, 10)
+		copies := cache.counter.copies.Load()
+		assert.Equal(t, 99, cache.merge(m))
+		assert.Equal(t, copies+1, cache.counter.copies.Load())
+		assert.Equal(t, 100, cache.Len())
+		v, _ := cache.JustGet(1)
+		assert.Equal(t, 10, v, "existing keys are kept")
+
+		bounded := RCUCache[int, int]{}
+		bounded.SetLimit(50, false)
+		assert.Equal(t, 50, bounded.merge(m))
+		assert.Equal(t, 50, bounded.Len())
+	})
+
+	t.Run("RCUCache-Limit", func(t *testing.T) {
+		cache := RCUCache[int, int]{
+			New: func(k int) int {
+				return k * 10
+			},
+		}
+		cache.SetLimit(100, false)
+		for i := 0; i < 1000; i++ {
+			assert.Equal(t, i*10, cache.Get(i))
+		}
+		assert.Equal(t, 100, cache.Len())
+		v, ok := cache.JustGet(99)
+		assert.True(t, ok)
+		assert.Equal(t, 990, v)
+		_, ok = cache.JustGet(100)
+		assert.False(t, ok)
+
+		cache.SetLimit(0, false)
+		assert.Equal(t, 100, cache.Len())
+		_, ok = cache.JustGet(99)
+		assert.True(t, ok)
+	})
+
+	t.Run("RCUCache-Evict", func(t *testing.T) {
+		cache := RCUCache[int, int]{}
+		cache.SetLimit(100, true)
+		wg := &sync.WaitGroup{}
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					cache.Set(g*1000+i, i)
+				}
+			}(g)
+		}
+		wg.Wait()
+		assert.LessOrEqual(t, cache.Len(), 100)
+		assert.Greater(t, cache.Len(), 0)
+		cache.Set(-1, -1)
+		v, ok := cache.JustGet(-1)
+		assert.True(t, ok)
+		assert.Equal(t, -1, v)
+	})
+
+	t.Run("StackCache-Limit", func(t *testing.T) {
+		cache := StackCache[int]{}
+		cache.SetLimit(10, true)
+		for i := 0; i < 100; i++ {
+			cache.SetPCs([]uintptr{uintptr(i), 1}, i)
+			v := cache.GetPCs([]uintptr{uintptr(i), 1})
+			assert.Equal(t, i, v)
+		}
+		assert.LessOrEqual(t, cache.Len(), 10)
+	})
 }
 
 /*
@@ -115,6 +218,62 @@ func BenchmarkCache(b *testing.B) {
 	}
 }
 
+/*
+go test -benchmem -run=^$ -bench "^(BenchmarkCache100k)$" github.com/lxt1045/errors -count=1 -v
+*/
+func BenchmarkCache100k(b *testing.B) {
+	const N = 100000
+	fill := func(c *RCUCache[int, int]) {
+		m := make(map[int]int, N)
+		for i := 0; i < N; i++ {
+			m[i] = i
+		}
+		c.cache = unsafe.Pointer(&m)
+	}
+	newCache := func(limit int, evict bool) *RCUCache[int, int] {
+		c := &RCUCache[int, int]{New: func(k int) int { return k }}
+		fill(c)
+		c.SetLimit(limit, evict)
+		return c
+	}
+
+	for _, bc := range []struct {
+		name  string
+		limit int
+		evict bool
+	}{
+		{"unbounded", 0, false},
+		{"bounded", 4 * N, false},
+		{"bounded-evict", N, true},
+	} {
+		c := newCache(bc.limit, bc.evict)
+		b.Run("Get/"+bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Get(i % N)
+			}
+		})
+		b.Run("Get-parallel/"+bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					c.Get(i % N)
+					i++
+				}
+			})
+		})
+		b.Run("Insert/"+bc.name, func(b *testing.B) {
+			c := newCache(bc.limit, bc.evict)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Get(N + i)
+			}
+		})
+	}
+}
+
 func Test_stackToNewStr(t *testing.T) {
 	mStr := map[string]int{}
 	const N, LK = 20, 32
@@ -24,10 +24,12 @@ package errors
 
 import (
 	"fmt"
+	"io"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog"
 )
@@ -40,45 +42,80 @@ const (
 var (
 	cacheStack   = StackCache[*callers]{}
 	cacheCallers = StackCache[[]caller]{}
-	cacheCaller  = RCUCache[uintptr, *caller]{}
+	cacheCaller  = RCUCache[callerKey, *caller]{}
+
+	stackDepth int32 = DefaultDepth // 全局的调用栈深度, 见 SetStackDepth
 
 	pool = sync.Pool{
-		New: func() any { return &[DefaultDepth]uintptr{} },
+		New: func() any {
+			pcs := make([]uintptr, DefaultDepth)
+			return &pcs
+		},
 	}
 )
 
+// SetStackDepth 设置 NewCode 等构建的调用栈深度, depth <= 0 时恢复为 DefaultDepth;
+// 只影响之后新建的 error
+func SetStackDepth(depth int) {
+	if depth <= 0 {
+		depth = DefaultDepth
+	}
+	atomic.StoreInt32(&stackDepth, int32(depth))
+}
+
+// StackDepth 返回当前全局的调用栈深度
+func StackDepth() int {
+	return int(atomic.LoadInt32(&stackDepth))
+}
+
+// getPCs 从 pool 中取出长度为 depth 且已清零的 pc 缓冲区, 用完后需 pool.Put 归还
+func getPCs(depth int) (p *[]uintptr, pcs []uintptr) {
+	p = pool.Get().(*[]uintptr)
+	if cap(*p) < depth {
+		*p = make([]uintptr, depth)
+	}
+	pcs = (*p)[:depth]
+	clear(pcs)
+	return
+}
+
 func NewCodeSlow(skip, code int, format string, a ...interface{}) (c *Code) {
 	if len(a) > 0 {
 		format = fmt.Sprintf(format, a...)
 	}
-	c = &Code{code: code, msg: format}
+	if skip >= 0 {
+		skip++
+	}
+	return countSite(newCodeSlow(skip, StackDepth(), GetFrameFilter(), code, format))
+}
 
+func NewCodeDepthSlow(skip, depth, code int, format string, a ...interface{}) (c *Code) {
+	if len(a) > 0 {
+		format = fmt.Sprintf(format, a...)
+	}
 	if skip >= 0 {
-		pcs := pool.Get().(*[DefaultDepth]uintptr)
-		n := runtime.Callers(skip+baseSkip, pcs[:DefaultDepth-skip])
-		// key := toString(pcs[:n])
+		skip++
+	}
+	return countSite(newCodeSlow(skip, depth, GetFrameFilter(), code, format))
+}
 
-		cs := cacheStack.Get(pcs, n)
-		if cs == nil {
-			cs = &callers{}
-			for _, c := range parseSlow(pcs[:n]) {
-				cs.stack = append(cs.stack, c.String())
-			}
-			l := 0
-			for i, str := range cs.stack {
-				// 检查是否需要转换 JSON 特殊字符串
-				lStack, yes := countEscape(str)
-				l += lStack
-				if yes {
-					cs.attr |= 1 << i
-				}
-			}
-			cs.attr |= uint64(l) << 32
+// newCodeSlow 只能被 NewCodeSlow 等直接调用, skip 需要已经包含它们自身
+func newCodeSlow(skip, depth int, filter *FrameFilter, code int, msg string) (c *Code) {
+	c = &Code{code: code, msg: msg}
+
+	if skip >= 0 {
+		mode := GetPathMode()
+		p, pcs := getPCs(depth + 3)
+		n := runtime.Callers(skip+baseSkip, pcs[:depth])
+		key := stackKey(pcs, n, mode, filter, 0) // key 包含路径格式和过滤器, 修改后不会命中旧的缓存
 
+		cs := cacheStack.GetPCs(key)
+		if cs == nil {
+			cs = toStackCallers(pcs[:n], mode, filter)
 			// 加入
-			cacheStack.Set(pcs, n, cs)
+			cacheStack.SetPCs(key, cs)
 		}
-		pool.Put(pcs)
+		pool.Put(p)
 		c.cache = cs
 	} else {
 		c.skip = DefaultDepth + 88
@@ -107,11 +144,9 @@ func NewErr(code int, format string, a ...interface{}) error {
 
 func NewCodeWithStack(code int, msg string, stack []string) error {
 	return &Code{
-		code: code,
-		msg:  msg,
-		cache: &callers{
-			stack: stack,
-		},
+		code:  code,
+		msg:   msg,
+		cache: newCallers(stack),
 	}
 }
 
@@ -137,6 +172,11 @@ type Code struct {
 
 	cache *callers
 	skip  int
+	err   error // 被 WithErr 包裹的原始错误, 可为 nil
+
+	fields []Field
+
+	mode ErrorMode // WithErrorMode 设置的 Error() 输出模式, 为 ErrorDefault 时跟随全局设置
 }
 
 func JoinStr(a, con, b string) string {
@@ -146,14 +186,15 @@ func JoinStr(a, con, b string) string {
 	return a + con + b
 }
 
+// WithErr 生成一个包含当前stack的新Code, 并把 err 作为其 cause 保留下来,
+// 因此 errors.Is/errors.As 仍能在结果上找到 err
+//
+// 结果的 Msg() 只返回 e 的 msg, err 的文本由 Error()、MarshalText、MarshalJSON 作为 cause 输出,
+// err 的类型用 Register 注册过时使用注册的函数生成文本
 func (e *Code) WithErr(err error) *Code {
-	if err == nil {
-		return NewCode(1, e.code, e.msg)
-	}
-	if f := getErrorFunc(err); f != nil {
-		return NewCode(1, e.code, JoinStr(e.msg, "; ", f(err)))
-	}
-	return NewCode(1, e.code, JoinStr(e.msg, "; ", err.Error()))
+	c := NewCode(1, e.code, e.msg)
+	c.err = err
+	return c
 }
 
 func (e *Code) Clone(msg ...string) *Code {
@@ -220,35 +261,125 @@ func (e *Code) Stack() (stack []string) {
 	return
 }
 
+// Unwrap 返回 WithErr 时保留的 cause
+func (e *Code) Unwrap() error {
+	return e.err
+}
+
 func (e *Code) Is(err error) bool {
 	to, ok := err.(*Code)
 	return ok && e.code != -1 && e.code == to.code
 }
 
-// Error error interface, 序列化为string, 包含调用栈
+// Error error interface, 输出内容由 WithErrorMode 或 SetErrorMode 决定, 默认包含调用栈
 func (e *Code) Error() string {
+	if mode := e.errorMode(); mode == ErrorMsg || mode == ErrorCaller {
+		return string(e.appendError(nil, mode))
+	}
+	if e.err != nil {
+		buf := &writeBuffer{}
+		marshalText(0, buf, e)
+		return buf.String()
+	}
 	cache := e.fmt()
 	buf := NewWriteBuffer(cache.textSize())
 	cache.text(buf)
 	return buf.String()
 }
 
+// Format fmt.Formatter 的方法, 语义同 github.com/pkg/errors:
+// %s、%v 只输出错误码和 msg (及 WithErr 的 cause), %+v 追加调用栈 (同 MarshalText),
+// %q 为带引号的 %s, %#v 输出 Go 语法的调试信息 (同 GoString);
+// 单行的 logfmt 使用 MarshalLogfmt 或 SetTextMode(TextLogfmt) 后的 %+v
+func (e *Code) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('#') {
+			io.WriteString(s, e.GoString())
+			return
+		}
+		if s.Flag('+') {
+			WriteText(s, e)
+			return
+		}
+		fallthrough
+	case 's':
+		buf := getBuffer()
+		buf.buf = e.appendError(buf.buf, ErrorMsg)
+		s.Write(buf.buf)
+		putBuffer(buf)
+	case 'q':
+		buf := getBuffer()
+		buf.buf = e.appendError(buf.buf, ErrorMsg)
+		fmt.Fprintf(s, "%q", buf.buf)
+		putBuffer(buf)
+	}
+}
+
+// appendError 按 ErrorMsg 或 ErrorCaller 模式追加 "code, msg[ caller][: cause]"
+func (e *Code) appendError(bs []byte, mode ErrorMode) []byte {
+	bs = strconv.AppendInt(bs, int64(e.code), 10)
+	bs = append(bs, ", "...)
+	bs = append(bs, e.msg...)
+	if mode == ErrorCaller {
+		if stack := e.Stack(); len(stack) > 0 {
+			bs = append(bs, ' ')
+			bs = append(bs, stack[0]...)
+		}
+	}
+	if e.err != nil {
+		bs = append(bs, ": "...)
+		bs = append(bs, causeText(e.err)...)
+	}
+	return bs
+}
+
+// GoString fmt.GoStringer 的方法, 用于 %#v
+func (e *Code) GoString() string {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "&errors.Code{code:%d, msg:%q", e.code, e.msg)
+	if stack := e.Stack(); len(stack) > 0 {
+		fmt.Fprintf(buf, ", stack:%#v", stack)
+	}
+	if len(e.fields) > 0 {
+		fmt.Fprintf(buf, ", fields:%#v", e.fields)
+	}
+	if e.err != nil {
+		fmt.Fprintf(buf, ", err:%#v", e.err)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
 // MarshalJSON json.Marshaler的方法, json.Marshal 里调用
 func (e *Code) MarshalJSON() (bs []byte, err error) {
-	cache := e.fmt()
-	buf := NewWriteBuffer(cache.jsonSize())
-	cache.json(buf)
+	if e.err != nil {
+		return MarshalJSON(e), nil
+	}
+	s, cache := getJSONSchema(), e.fmt()
+	buf := NewWriteBuffer(cache.jsonSize(s))
+	cache.json(buf, s)
 	return buf.Bytes(), nil
 }
 func parseSlow(pcs []uintptr) (cs []caller) {
+	cs, _ = parseFrames(pcs, GetPathMode(), nil)
+	return
+}
+
+// parseFrames 解析 pcs, 去掉 filter 匹配的 frame; kept 为保留下来的 frame 对应的 pc
+func parseFrames(pcs []uintptr, mode PathMode, filter *FrameFilter) (cs []caller, kept []uintptr) {
 	traces, more, f := runtime.CallersFrames(pcs), true, runtime.Frame{}
-	for more {
+	for i := 0; more; i++ {
 		f, more = traces.Next()
-		c := toCaller(f)
-		if skipFile(c.FileLine) && len(cs) > 0 {
+		if skipFile(lastSegments(f.File, 2)) && len(cs) > 0 {
 			break
 		}
-		cs = append(cs, c)
+		if !filter.skip(&f) {
+			cs = append(cs, toCallerMode(f, mode))
+			if i < len(pcs) {
+				kept = append(kept, pcs[i])
+			}
+		}
 		if strings.HasSuffix(f.Function, "main.main") && len(cs) > 0 {
 			break
 		}
@@ -256,33 +387,93 @@ func parseSlow(pcs []uintptr) (cs []caller) {
 	return
 }
 func (e *Code) fmt() (cs fmtCode) {
-	return fmtCode{code: strconv.Itoa(e.code), msg: e.msg, callers: e.cache, skip: e.skip}
+	return fmtCode{code: e.code, msg: e.msg, callers: e.cache, skip: e.skip, fields: e.fields}
 }
 
 type callers struct {
-	stack []string
-	attr  uint64 // count:escape ==> uint32:uint32
+	stack  []string
+	attr   uint64    // count:_ ==> uint32:uint32, count 为 JSON 转义后 stack 的总长度
+	escape []uint64  // 每个 frame 占一个 bit, 标记是否需要 JSON 转义
+	pcs    []uintptr // 与 stack 一一对应的 pc; 由 NewCodeWithStack 等生成时为 nil
+	filter uintptr   // 解析时使用的 FrameFilter 的 id, 没有时为 0; 供 ExportSnapshot 区分 cacheStack 的 key
+}
+
+// newCallers 用已格式化好的 stack 生成 callers, 并计算 JSON 转义信息
+func newCallers(stack []string) *callers {
+	cs := &callers{stack: stack}
+	l := 0
+	for i, str := range stack {
+		// 检查是否需要转换 JSON 特殊字符串
+		lStack, yes := countEscape(str)
+		l += lStack
+		if yes {
+			if cs.escape == nil {
+				cs.escape = make([]uint64, (len(stack)+63)/64)
+			}
+			cs.escape[i/64] |= 1 << (i % 64)
+		}
+	}
+	cs.attr |= uint64(l) << 32
+	return cs
+}
+
+// toStackCallers 解析 pcs 并生成 callers
+func toStackCallers(pcs []uintptr, mode PathMode, filter *FrameFilter) *callers {
+	cs, kept := parseFrames(pcs, mode, filter)
+	stack := make([]string, 0, len(cs))
+	for _, c := range cs {
+		stack = append(stack, c.String())
+	}
+	c := newCallers(stack)
+	if len(kept) == len(stack) {
+		c.pcs = kept
+	}
+	if filter != nil {
+		c.filter = filter.id
+	}
+	return c
+}
+
+// needEscape 第 i 个 frame 是否需要 JSON 转义
+func (cs *callers) needEscape(i int) bool {
+	return i/64 < len(cs.escape) && cs.escape[i/64]&(1<<(i%64)) != 0
 }
+
 type fmtCode struct {
-	code      string
+	code      int
 	msg       string
 	skip      int
 	msgEscape bool
 	*callers
+
+	fields    []Field
+	fieldsBuf []byte // jsonSize 或 textSize 时预先序列化的 fields
 }
 
-func (f *fmtCode) jsonSize() (l int) {
-	l, f.msgEscape = countEscape(f.msg)
-	l += len(f.code) + len(`{"code":,"msg":""}`)
-	if len(f.stack) <= f.skip {
+func (f *fmtCode) jsonSize(s *jsonSchema) (l int) {
+	l = len(`{}`) + len(s.code) + intLen(f.code)
+	if s.keep(f.msg) {
+		n, escape := countEscape(f.msg)
+		l += len(`,""`) + len(s.msg) + n
+		f.msgEscape = escape
+	}
+	if len(f.fields) > 0 {
+		f.fieldsBuf = appendFieldsJSON(f.fieldsBuf[:0], s.fields, f.fields)
+		l += len(f.fieldsBuf)
+	}
+	if f.callers == nil || len(f.stack) <= f.skip {
 		return
 	}
-	l += len(`,"stack":[]`) + (len(f.stack)-f.skip)*len(`,""`) - len(`,`) + (int(f.attr) >> 32)
+	l += s.stackSize(len(f.stack)-f.skip, int(f.attr)>>32)
 	return
 }
 
 func (f *fmtCode) textSize() (l int) {
-	l = len(", ") + len(f.code) + len(f.msg)
+	l = len(", ") + intLen(f.code) + len(f.msg)
+	if len(f.fields) > 0 {
+		f.fieldsBuf = appendFieldsText(f.fieldsBuf[:0], f.fields)
+		l += len(f.fieldsBuf)
+	}
 	if f.callers == nil || len(f.stack) <= f.skip {
 		return
 	}
@@ -293,39 +484,33 @@ func (f *fmtCode) textSize() (l int) {
 	return
 }
 
-func (f *fmtCode) json(buf *writeBuffer) {
-	buf.WriteString(`{"code":`)
-	buf.WriteString(f.code)
-	buf.WriteString(`,"msg":"`)
-	if !f.msgEscape {
-		buf.WriteString(f.msg)
-	} else {
-		buf.WriteEscape(f.msg)
-	}
-	buf.WriteByte('"')
-	if len(f.stack) > f.skip {
-		buf.WriteString(`,"stack":[`)
-		for i, str := range f.stack[f.skip:] {
-			if i != 0 {
-				buf.WriteByte(',')
-			}
-			buf.WriteByte('"')
-			if f.attr&(1<<i) == 0 {
-				buf.WriteString(str)
-			} else {
-				buf.WriteEscape(str)
-			}
-			buf.WriteByte('"')
+func (f *fmtCode) json(buf *writeBuffer, s *jsonSchema) {
+	buf.WriteByte('{')
+	buf.WriteString(s.code)
+	buf.WriteInt(f.code)
+	if s.keep(f.msg) {
+		buf.WriteByte(',')
+		buf.WriteString(s.msg)
+		buf.WriteByte('"')
+		if !f.msgEscape {
+			buf.WriteString(f.msg)
+		} else {
+			buf.WriteEscape(f.msg)
 		}
-		buf.WriteByte(']')
+		buf.WriteByte('"')
+	}
+	buf.Write(f.fieldsBuf)
+	if f.callers != nil && len(f.stack) > f.skip {
+		buf.buf = s.appendStack(buf.buf, f.callers, f.skip, len(f.stack))
 	}
 	buf.WriteByte('}')
 }
 
 func (f *fmtCode) text(buf *writeBuffer) {
-	buf.WriteString(f.code)
+	buf.WriteInt(f.code)
 	buf.WriteString(", ")
 	buf.WriteString(f.msg)
+	buf.Write(f.fieldsBuf)
 	if f.callers != nil && len(f.stack) > f.skip {
 		buf.WriteString(";\n")
 		for i, str := range f.stack[f.skip:] {
@@ -343,6 +528,9 @@ func (f *fmtCode) text(buf *writeBuffer) {
 func (e *Code) MarshalZerologObject(evt *zerolog.Event) {
 	evt.Int("code", e.code)
 	evt.Str("msg", e.msg)
+	for _, f := range Fields(e) {
+		zerologField(evt, f)
+	}
 	evt.Array("stack", e)
 }
 
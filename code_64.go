@@ -13,41 +13,55 @@ func NewCode(skip, code int, format string, a ...interface{}) (c *Code) {
 	if len(a) > 0 {
 		format = fmt.Sprintf(format, a...)
 	}
-	c = &Code{code: code, msg: format, skip: skip}
+	return countSite(newCode(skip, StackDepth(), GetFrameFilter(), code, format))
+}
+
+// NewCodeDepth 同 NewCode, 但调用栈深度由 depth 指定, 而不是全局的 StackDepth()
+func NewCodeDepth(skip, depth, code int, format string, a ...interface{}) (c *Code) {
+	if len(a) > 0 {
+		format = fmt.Sprintf(format, a...)
+	}
+	return countSite(newCode(skip, depth, GetFrameFilter(), code, format))
+}
+
+// NewCode 同 errors.NewCode, 但使用 f 过滤调用栈, 而不是全局的 FrameFilter
+func (f *FrameFilter) NewCode(skip, code int, format string, a ...interface{}) (c *Code) {
+	if len(a) > 0 {
+		format = fmt.Sprintf(format, a...)
+	}
+	return countSite(newCode(skip, StackDepth(), f, code, format))
+}
+
+// newStackCode 同 NewCode(skip, DefaultCode, ""), 但不计入 TopSites, 供 WrapStack、Join 记录调用栈
+//
+//go:noinline
+func newStackCode(skip int) *Code {
+	return newCode(skip, StackDepth(), GetFrameFilter(), DefaultCode, "")
+}
+
+// newCode 只能被 NewCode 等直接调用, 生成的调用栈从它们的调用方开始
+func newCode(skip, depth int, filter *FrameFilter, code int, msg string) (c *Code) {
+	c = &Code{code: code, msg: msg, skip: skip}
 	if skip >= 0 {
-		pcs := pool.Get().(*[DefaultDepth]uintptr)
-		for i := range pcs {
-			pcs[i] = 0
-		}
-		n := buildStack(pcs[:])
-		// for i := n; i < DefaultDepth; i++ {
-		// 	pcs[i] = 0
-		// }
+		mode := GetPathMode()
+		p, pcs := getPCs(depth + 3)
+		n := buildStack(pcs[:depth:depth])
+		key := stackKey(pcs, n, mode, filter, skip) // key 包含路径格式和过滤器, 修改后不会命中旧的缓存
 
 		//
-		cs := cacheStack.Get(pcs, n)
+		cs := cacheStack.GetPCs(key)
 		if cs == nil {
-			pcs1 := make([]uintptr, DefaultDepth)
-			npc1 := runtime.Callers(baseSkip, pcs1[:DefaultDepth])
-			cs = &callers{}
-			for _, c := range parseSlow(pcs1[:npc1]) {
-				cs.stack = append(cs.stack, c.String())
-			}
-			l := 0
-			for i, str := range cs.stack {
-				// 检查是否需要转换 JSON 特殊字符串
-				lStack, yes := countEscape(str)
-				l += lStack
-				if yes {
-					cs.attr |= 1 << i
-				}
-			}
-			cs.attr |= uint64(l) << 32
-
-			cacheStack.Set(pcs, n, cs)
+			pcs1 := make([]uintptr, depth)
+			npc1 := runtime.Callers(baseSkip+1, pcs1)
+			cs = toStackCallers(skipPCs(pcs1[:npc1], filter, skip), mode, filter)
+
+			cacheStack.SetPCs(key, cs)
 		}
-		pool.Put(pcs)
+		pool.Put(p)
 		c.cache = cs
+		if filter != nil {
+			c.skip = 0 // 已经在过滤之前处理了 skip
+		}
 	} else {
 		c.skip = DefaultDepth + 88
 	}
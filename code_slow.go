@@ -3,4 +3,28 @@
 
 package errors
 
+import "fmt"
+
 var NewCode = NewCodeSlow
+var NewCodeDepth = NewCodeDepthSlow
+
+// NewCode 同 errors.NewCode, 但使用 f 过滤调用栈, 而不是全局的 FrameFilter
+func (f *FrameFilter) NewCode(skip, code int, format string, a ...interface{}) (c *Code) {
+	if len(a) > 0 {
+		format = fmt.Sprintf(format, a...)
+	}
+	if skip >= 0 {
+		skip++
+	}
+	return countSite(newCodeSlow(skip, StackDepth(), f, code, format))
+}
+
+// newStackCode 同 NewCode(skip, DefaultCode, ""), 但不计入 TopSites, 供 WrapStack、Join 记录调用栈
+//
+//go:noinline
+func newStackCode(skip int) *Code {
+	if skip >= 0 {
+		skip++
+	}
+	return newCodeSlow(skip, StackDepth(), GetFrameFilter(), DefaultCode, "")
+}
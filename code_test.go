@@ -28,13 +28,8 @@ var (
 )
 
 func TestMain(m *testing.M) {
-	// mFrames[testFrame[0]] = frame{stack: testFrameFunc, attr: uint64(len(testFrameFunc)) << 32}
-	mFramesCache = func() unsafe.Pointer {
-		m := map[uintptr]*frame{
-			testFrame[0]: &frame{stack: testFrameFunc, attr: uint64(len(testFrameFunc)) << 32},
-		}
-		return unsafe.Pointer(&m)
-	}()
+	cacheWrapper.Set([2]uintptr{testFrame[0], uintptr(GetPathMode())},
+		&frame{stack: testFrameFunc, attr: uint64(len(testFrameFunc)) << 32})
 	m.Run()
 }
 
@@ -116,6 +111,50 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func Test_StackDepth(t *testing.T) {
+	t.Run("NewCodeDepth", func(t *testing.T) {
+		var e, e1 *Code
+		deepCall(DefaultDepth+10, func() {
+			e = NewCodeDepth(0, DefaultDepth*2, errCode, errMsg)
+			e1 = NewCode(0, errCode, errMsg)
+		})
+		assert.True(t, len(e.Stack()) > DefaultDepth)
+		assert.Equal(t, DefaultDepth, len(e1.Stack()))
+		assert.Equal(t, e1.Stack()[1:], e.Stack()[1:DefaultDepth])
+	})
+
+	t.Run("SetStackDepth", func(t *testing.T) {
+		SetStackDepth(8)
+		defer SetStackDepth(0)
+		assert.Equal(t, 8, StackDepth())
+
+		var e *Code
+		var cs []caller
+		deepCall(DefaultDepth, func() {
+			e = NewCode(0, errCode, errMsg)
+			cs = CallersSkip(0)
+		})
+		assert.Equal(t, 8, len(e.Stack()))
+		assert.Equal(t, 8, len(cs))
+	})
+
+	t.Run("escape", func(t *testing.T) {
+		stack := make([]string, DefaultDepth+8)
+		for i := range stack {
+			stack[i] = "(file.go:1) func" + strconv.Itoa(i)
+		}
+		stack[DefaultDepth+4] = `(file.go:1) "func"`
+		e := NewCodeWithStack(errCode, errMsg, stack).(*Code)
+		assert.True(t, e.cache.needEscape(DefaultDepth+4))
+		assert.False(t, e.cache.needEscape(DefaultDepth+3))
+
+		m := map[string]interface{}{}
+		bs, _ := e.MarshalJSON()
+		assert.Nil(t, json.Unmarshal(bs, &m))
+		assert.Equal(t, stack[DefaultDepth+4], m["stack"].([]interface{})[DefaultDepth+4])
+	})
+}
+
 func Test_NewCodeWithStack(t *testing.T) {
 	t.Run("NewCodeWithStack", func(t *testing.T) {
 		code := NewCodeWithStack(2222, "test", []string{
@@ -196,7 +235,81 @@ func Test_Code(t *testing.T) {
 		assert.Equal(t, c.Error(), str)
 		errStr := str
 		assert.Equal(t, c.Error(), errStr)
-		assert.Equal(t, fmt.Sprint(c), errStr)
+		assert.Equal(t, fmt.Sprintf("%+v", c), errStr)
+		assert.Equal(t, "88888, msg!", fmt.Sprint(c))
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		c := &Code{
+			code: errCode,
+			msg:  errMsg,
+			cache: &callers{
+				stack: []string{"(file1:88) func1"},
+			},
+		}
+		assert.Equal(t, "88888, msg!", fmt.Sprintf("%s", c))
+		assert.Equal(t, "88888, msg!", fmt.Sprintf("%v", c))
+		assert.Equal(t, `"88888, msg!"`, fmt.Sprintf("%q", c))
+		assert.Equal(t, c.Error(), fmt.Sprintf("%+v", c))
+		assert.Equal(t, `&errors.Code{code:88888, msg:"msg!", stack:[]string{"(file1:88) func1"}}`, fmt.Sprintf("%#v", c))
+
+		w := &Code{code: errCode, msg: errMsg, err: stderrors.New("EOF")}
+		assert.Equal(t, "88888, msg!: EOF", fmt.Sprint(w))
+		assert.Equal(t, `&errors.Code{code:88888, msg:"msg!", err:&errors.errorString{s:"EOF"}}`, fmt.Sprintf("%#v", w))
+
+		// wrapper、Join 的 %#v 同样是 Go 语法的调试信息
+		wp := &wrapper{msg: errTrace, parsed: newFrame("(file2:99) func2"), err: c}
+		assert.Equal(t, `&errors.wrapper{msg:"trace!", caller:"(file2:99) func2", err:&errors.Code{code:88888, msg:"msg!", stack:[]string{"(file1:88) func1"}}}`, fmt.Sprintf("%#v", wp))
+		j := &joinError{errs: []error{c, wp}}
+		assert.Equal(t, `&errors.joinError{errs:[]error{`+c.GoString()+`, `+wp.GoString()+`}}`, fmt.Sprintf("%#v", j))
+		assert.Equal(t, "wrap: 88888, msg!", fmt.Sprintf("%v", fmt.Errorf("wrap: %v", c)))
+	})
+
+	t.Run("WithErr", func(t *testing.T) {
+		cause := stderrors.New(errMsg)
+		c := NewCode(0, errCode, errTrace).WithErr(cause)
+		assert.Equal(t, c.Code(), errCode)
+		assert.Equal(t, c.Msg(), errTrace)
+		assert.Equal(t, c.Unwrap(), cause)
+		assert.True(t, stderrors.Is(c, cause))
+		assert.True(t, Is(c, cause))
+
+		var target *json.SyntaxError
+		c = NewCode(0, errCode, errTrace).WithErr(Wrap(&json.SyntaxError{Offset: 1}, errTrace))
+		assert.True(t, stderrors.As(c, &target))
+
+		// 输出 cause 时使用 Register 注册的函数
+		_ = Register(&json.UnsupportedValueError{}, func(err error) string { return "registered" })
+		cause = &json.UnsupportedValueError{Str: "secret"}
+		c = NewCode(0, errCode, errTrace).WithErr(cause)
+		assert.Equal(t, errTrace, c.Msg())
+		for _, s := range []string{c.Error(), string(MarshalText(c)), string(MarshalJSON(c)), string(MarshalJSON2(c)),
+			string(MarshalLogfmt(c)), fmt.Sprint(c), c.WithErrorMode(ErrorMsg).Error(), string(MarshalText(Wrap(cause, errTrace)))} {
+			assert.Contains(t, s, "registered")
+			assert.NotContains(t, s, "secret")
+		}
+		assert.NotNil(t, Register(&Code{}, nil))
+	})
+
+	t.Run("WithErr.format", func(t *testing.T) {
+		c := &Code{
+			code: errCode,
+			msg:  errMsg,
+			cache: &callers{
+				stack: []string{"(file1:88) func1"},
+			},
+			err: stderrors.New(errTrace),
+		}
+		str := "trace!;\n88888, msg!;\n    (file1:88) func1;"
+		assert.Equal(t, str, c.Error())
+		assert.Equal(t, str, string(MarshalText(c)))
+
+		str = `{"cause":"trace!","wrapper":[{"code":88888,"msg":"msg!","stack":["(file1:88) func1"]}]}`
+		assert.Equal(t, str, string(MarshalJSON(c)))
+		assert.Equal(t, str, string(MarshalJSON2(c)))
+		bs, err := json.Marshal(c)
+		assert.Nil(t, err)
+		assert.Equal(t, str, string(bs))
 	})
 }
 
@@ -307,16 +420,16 @@ func BenchmarkEscape(b *testing.B) {
 	b.Run("escape", func(b *testing.B) {
 		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
-			pcs := pool.Get().(*[DefaultDepth]uintptr)
-			_ = buildStack(pcs[:])
-			pool.Put(pcs)
+			p, pcs := getPCs(DefaultDepth)
+			_ = buildStack(pcs)
+			pool.Put(p)
 		}
 	})
 	b.Run("pool", func(b *testing.B) {
 		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
-			pcs := pool.Get().(*[DefaultDepth]uintptr)
-			pool.Put(pcs)
+			p := pool.Get().(*[]uintptr)
+			pool.Put(p)
 		}
 	})
 
@@ -0,0 +1,65 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import "sync/atomic"
+
+// ErrorMode (*Code).Error() 和 Wrap 等生成的 error 的 Error() 输出内容;
+// 无论哪种模式, 调用栈都可以通过 %+v、MarshalJSON 和 Stack() 获取
+type ErrorMode int32
+
+const (
+	ErrorDefault ErrorMode = iota // 跟随 SetErrorMode 设置的全局模式
+	ErrorFull                     // 包含完整调用栈, 同 %+v; 全局默认值
+	ErrorMsg                      // 只有错误码和 msg, 如 "1001, not found: cause"
+	ErrorCaller                   // 错误码、msg 和调用位置, 如 "1001, not found (internal/user.go:12) user.Get"
+)
+
+var errorMode int32 = int32(ErrorFull) // 全局的 Error() 输出模式
+
+// SetErrorMode 设置全局的 Error() 输出模式, ErrorDefault 等同于 ErrorFull
+func SetErrorMode(mode ErrorMode) {
+	if mode == ErrorDefault {
+		mode = ErrorFull
+	}
+	atomic.StoreInt32(&errorMode, int32(mode))
+}
+
+// GetErrorMode 返回全局的 Error() 输出模式
+func GetErrorMode() ErrorMode {
+	return ErrorMode(atomic.LoadInt32(&errorMode))
+}
+
+// WithErrorMode 返回 Error() 按 mode 输出的 Code 副本, 不受全局设置影响; mode 为 ErrorDefault 时恢复跟随全局设置
+func (e *Code) WithErrorMode(mode ErrorMode) *Code {
+	c := *e
+	c.mode = mode
+	return &c
+}
+
+func (e *Code) errorMode() ErrorMode {
+	if e.mode != ErrorDefault {
+		return e.mode
+	}
+	return GetErrorMode()
+}
@@ -0,0 +1,70 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorMode(t *testing.T) {
+	defer SetErrorMode(ErrorDefault)
+
+	c := &Code{code: errCode, msg: errMsg, cache: newCallers([]string{"(file1:88) func1", "(file2:99) func2"})}
+	cause := *c
+	cause.err = io.EOF
+	w := Wrap(&cause, errTrace)
+	full := c.Error()
+	assert.Equal(t, ErrorFull, GetErrorMode())
+	assert.Equal(t, "88888, msg!;\n    (file1:88) func1, \n    (file2:99) func2;", full)
+
+	SetErrorMode(ErrorMsg)
+	assert.Equal(t, "88888, msg!", c.Error())
+	assert.Equal(t, "trace!: 88888, msg!: EOF", w.Error())
+	assert.Equal(t, "88888, load: EOF", WrapCode(io.EOF, c, "load").Error())
+	assert.Equal(t, full, fmt.Sprintf("%+v", c))
+	assert.Equal(t, []string{"(file1:88) func1", "(file2:99) func2"}, c.Stack())
+	assert.Contains(t, string(MarshalJSON(c)), `"stack":["(file1:88) func1"`)
+
+	SetErrorMode(ErrorCaller)
+	assert.Equal(t, "88888, msg! (file1:88) func1", c.Error())
+	s := w.Error()
+	assert.True(t, strings.HasPrefix(s, "trace! (module/errmode_test.go:"), s)
+	assert.True(t, strings.HasSuffix(s, ": 88888, msg! (file1:88) func1: EOF"), s)
+
+	t.Run("WithErrorMode", func(t *testing.T) {
+		c2 := c.WithErrorMode(ErrorFull)
+		assert.Equal(t, full, c2.Error())
+		assert.Equal(t, "88888, msg! (file1:88) func1", c.Error())
+		SetErrorMode(ErrorMsg)
+		assert.Equal(t, full, c2.Error())
+		assert.Equal(t, "88888, msg!", c2.WithErrorMode(ErrorDefault).Error())
+	})
+}
+
+func TestErrorModeJoin(t *testing.T) {
+	defer SetErrorMode(ErrorDefault)
+
+	c := &Code{code: errCode, msg: errMsg, cache: newCallers([]string{"(file1:88) func1"})}
+	j := &joinError{errs: []error{c, io.EOF}, cache: newCallers([]string{"(file3:7) func3", "(file4:8) func4"})}
+	w := Wrap(j, errTrace)
+
+	full := j.Error()
+	assert.Contains(t, full, "(file3:7) func3")
+	assert.Contains(t, full, "\n")
+
+	SetErrorMode(ErrorMsg)
+	assert.Equal(t, "[88888, msg!; EOF]", j.Error())
+	assert.Equal(t, "trace!: [88888, msg!; EOF]", w.Error())
+
+	SetErrorMode(ErrorCaller)
+	assert.Equal(t, "[88888, msg! (file1:88) func1; EOF] (file3:7) func3", j.Error())
+	s := w.Error()
+	assert.True(t, strings.HasPrefix(s, "trace! (module/errmode_test.go:"), s)
+	assert.True(t, strings.HasSuffix(s, ": [88888, msg! (file1:88) func1; EOF] (file3:7) func3"), s)
+
+	SetErrorMode(ErrorFull)
+	assert.Equal(t, full, j.Error())
+}
@@ -0,0 +1,290 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Field 附加在 Code 或 Wrap 层上的结构化 key/value
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// toFields 把 kvs 转换为 Field 追加到 fs 之后;
+// kvs 的元素可以是 Field, 也可以是 key, value 交替出现, 缺失的 value 为 nil
+func toFields(fs []Field, kvs []interface{}) []Field {
+	for i := 0; i < len(kvs); i++ {
+		switch k := kvs[i].(type) {
+		case Field:
+			fs = append(fs, k)
+			continue
+		case string:
+			f := Field{Key: k}
+			if i+1 < len(kvs) {
+				i++
+				f.Value = kvs[i]
+			}
+			fs = append(fs, f)
+		default:
+			f := Field{Key: fmt.Sprint(k)}
+			if i+1 < len(kvs) {
+				i++
+				f.Value = kvs[i]
+			}
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+// With 返回附加了 kvs 的 Code 副本, 不会修改 e 本身, 因此可以放心地用在全局定义的 Code 上
+func (e *Code) With(kvs ...interface{}) *Code {
+	c := *e
+	c.fields = toFields(e.fields[:len(e.fields):len(e.fields)], kvs)
+	return &c
+}
+
+// Fields 返回 e 自身附加的 fields, 不包含 cause 上的
+func (e *Code) Fields() []Field {
+	return e.fields
+}
+
+// With 返回附加了 kvs 的 wrapper 副本
+func (e *wrapper) With(kvs ...interface{}) error {
+	w := *e
+	w.fields = toFields(e.fields[:len(e.fields):len(e.fields)], kvs)
+	return &w
+}
+
+// With 给 err 最外层的 Code 或 wrapper 附加 kvs; 其它类型的 err 会先被 Wrap 一层
+//
+//go:noinline
+func With(err error, kvs ...interface{}) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *Code:
+		return e.With(kvs...)
+	case *wrapper:
+		return e.With(kvs...)
+	}
+	return &wrapper{
+		pc:     getPC(),
+		err:    err,
+		fields: toFields(nil, kvs),
+	}
+}
+
+// Fields 同 Layers 按深度优先遍历 err, 包括 Join 的各个分支, 收集所有 fields, 外层在前
+func Fields(err error) (fs []Field) {
+	walk(err, 0, func(err error, _ int) bool {
+		fs = append(fs, fieldsOf(err)...)
+		return true
+	})
+	return
+}
+
+// FieldValue 按 Fields 的顺序查找 key, 外层、靠前的分支的值优先
+func FieldValue(err error, key string) (v interface{}, ok bool) {
+	walk(err, 0, func(err error, _ int) bool {
+		fs := fieldsOf(err)
+		for i := len(fs) - 1; i >= 0; i-- {
+			if fs[i].Key == key {
+				v, ok = fs[i].Value, true
+				return false
+			}
+		}
+		return true
+	})
+	return
+}
+
+func fieldsOf(err error) []Field {
+	switch e := err.(type) {
+	case *Code:
+		return e.fields
+	case *wrapper:
+		return e.fields
+	}
+	return nil
+}
+
+// appendFieldsJSON 追加 `,"fields":{...}`, key 为带引号和冒号的字段名
+func appendFieldsJSON(bs []byte, key string, fs []Field) []byte {
+	bs = append(bs, ',')
+	bs = append(bs, key...)
+	bs = append(bs, '{')
+	for i, f := range fs {
+		if i != 0 {
+			bs = append(bs, ',')
+		}
+		bs = append(bs, '"')
+		bs = appendEscape(bs, f.Key)
+		bs = append(bs, `":`...)
+		bs = appendValueJSON(bs, f.Value)
+	}
+	return append(bs, '}')
+}
+
+func appendValueJSON(bs []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case nil:
+		return append(bs, `null`...)
+	case string:
+		return appendQuoteJSON(bs, x)
+	case bool:
+		return strconv.AppendBool(bs, x)
+	case int:
+		return strconv.AppendInt(bs, int64(x), 10)
+	case int8:
+		return strconv.AppendInt(bs, int64(x), 10)
+	case int16:
+		return strconv.AppendInt(bs, int64(x), 10)
+	case int32:
+		return strconv.AppendInt(bs, int64(x), 10)
+	case int64:
+		return strconv.AppendInt(bs, x, 10)
+	case uint:
+		return strconv.AppendUint(bs, uint64(x), 10)
+	case uint8:
+		return strconv.AppendUint(bs, uint64(x), 10)
+	case uint16:
+		return strconv.AppendUint(bs, uint64(x), 10)
+	case uint32:
+		return strconv.AppendUint(bs, uint64(x), 10)
+	case uint64:
+		return strconv.AppendUint(bs, x, 10)
+	case float32:
+		return appendFloatJSON(bs, float64(x), 32)
+	case float64:
+		return appendFloatJSON(bs, x, 64)
+	case time.Duration:
+		return appendQuoteJSON(bs, x.String())
+	case error:
+		return appendQuoteJSON(bs, x.Error())
+	case json.Marshaler:
+	case fmt.Stringer:
+		return appendQuoteJSON(bs, x.String())
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appendQuoteJSON(bs, fmt.Sprint(v))
+	}
+	return append(bs, b...)
+}
+
+func appendFloatJSON(bs []byte, f float64, bitSize int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		bs = append(bs, '"')
+		bs = strconv.AppendFloat(bs, f, 'g', -1, bitSize)
+		return append(bs, '"')
+	}
+	return strconv.AppendFloat(bs, f, 'g', -1, bitSize)
+}
+
+func appendQuoteJSON(bs []byte, s string) []byte {
+	bs = append(bs, '"')
+	bs = appendEscape(bs, s)
+	return append(bs, '"')
+}
+
+// appendFieldsText 追加 ` k1=v1 k2=v2`, 含有空白或特殊字符的值会加引号
+func appendFieldsText(bs []byte, fs []Field) []byte {
+	for _, f := range fs {
+		bs = append(bs, ' ')
+		bs = appendTextValue(bs, f.Key)
+		bs = append(bs, '=')
+		switch x := f.Value.(type) {
+		case string:
+			bs = appendTextValue(bs, x)
+		case error:
+			bs = appendTextValue(bs, x.Error())
+		case fmt.Stringer:
+			bs = appendTextValue(bs, x.String())
+		default:
+			bs = appendTextValue(bs, fmt.Sprint(x))
+		}
+	}
+	return bs
+}
+
+func appendTextValue(bs []byte, s string) []byte {
+	if needQuote(s) {
+		return strconv.AppendQuote(bs, s)
+	}
+	return append(bs, s...)
+}
+
+func needQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func zerologField(evt *zerolog.Event, f Field) {
+	switch x := f.Value.(type) {
+	case string:
+		evt.Str(f.Key, x)
+	case bool:
+		evt.Bool(f.Key, x)
+	case int:
+		evt.Int(f.Key, x)
+	case int32:
+		evt.Int32(f.Key, x)
+	case int64:
+		evt.Int64(f.Key, x)
+	case uint:
+		evt.Uint(f.Key, x)
+	case uint32:
+		evt.Uint32(f.Key, x)
+	case uint64:
+		evt.Uint64(f.Key, x)
+	case float32:
+		evt.Float32(f.Key, x)
+	case float64:
+		evt.Float64(f.Key, x)
+	case time.Duration:
+		evt.Dur(f.Key, x)
+	case time.Time:
+		evt.Time(f.Key, x)
+	case error:
+		evt.Str(f.Key, x.Error())
+	default:
+		evt.Interface(f.Key, x)
+	}
+}
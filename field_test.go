@@ -0,0 +1,69 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrs "errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestField(t *testing.T) {
+	t.Run("Code.With", func(t *testing.T) {
+		base := NewCode(0, errCode, errMsg)
+		c := base.With("user_id", 1, "name", "a b")
+		assert.Nil(t, base.Fields())
+		assert.Equal(t, []Field{{"user_id", 1}, {"name", "a b"}}, c.Fields())
+
+		c = &Code{code: errCode, msg: errMsg, cache: &callers{stack: []string{"(file1:88) func1"}}}
+		c = c.With("user_id", 1, Field{Key: "name", Value: "a b"})
+		assert.Equal(t, "88888, msg! user_id=1 name=\"a b\";\n    (file1:88) func1;", c.Error())
+		bs, err := json.Marshal(c)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"code":88888,"msg":"msg!","fields":{"user_id":1,"name":"a b"},"stack":["(file1:88) func1"]}`, string(bs))
+	})
+
+	t.Run("Wrap.With", func(t *testing.T) {
+		e := Wrap(stderrs.New(errMsg), errTrace)
+		e.(*wrapper).pc = testFrame
+		e = With(e, "order_id", int64(7))
+		str := `{"cause":"msg!","wrapper":[{"trace":"trace!","caller":"(file1:88) func1","fields":{"order_id":7}}]}`
+		assert.Equal(t, str, string(MarshalJSON(e)))
+		assert.Equal(t, str, string(MarshalJSON2(e)))
+		assert.Equal(t, "msg!;\ntrace! order_id=7,\n    (file1:88) func1;", string(MarshalText(e)))
+	})
+
+	t.Run("Fields", func(t *testing.T) {
+		err := With(stderrs.New(errMsg), "k", "v")
+		assert.Equal(t, []Field{{"k", "v"}}, Fields(err))
+
+		err = NewCode(0, errCode, errMsg).With("user_id", 1, "retry_after", 3)
+		err = With(Wrap(err, errTrace), "order_id", 2, "user_id", 9)
+		assert.Equal(t, []Field{{"order_id", 2}, {"user_id", 9}, {"user_id", 1}, {"retry_after", 3}}, Fields(err))
+		v, ok := FieldValue(err, "user_id")
+		assert.True(t, ok)
+		assert.Equal(t, 9, v)
+		v, ok = FieldValue(err, "retry_after")
+		assert.True(t, ok)
+		assert.Equal(t, 3, v)
+		_, ok = FieldValue(err, "none")
+		assert.False(t, ok)
+	})
+
+	t.Run("Join", func(t *testing.T) {
+		a := NewCode(0, errCode, errMsg).With("user_id", 1)
+		b := With(stderrs.New(errMsg), "order_id", 2, "user_id", 3)
+		for _, err := range []error{
+			With(Join(a, b), "req_id", "r"),
+			With(stderrs.Join(a, b), "req_id", "r"),
+		} {
+			assert.Equal(t, []Field{{"req_id", "r"}, {"user_id", 1}, {"order_id", 2}, {"user_id", 3}}, Fields(err))
+			v, ok := FieldValue(err, "order_id")
+			assert.True(t, ok)
+			assert.Equal(t, 2, v)
+			v, ok = FieldValue(err, "user_id")
+			assert.True(t, ok)
+			assert.Equal(t, 1, v)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+var (
+	frameFilterID uintptr                     // FrameFilter 的自增 id, 作为缓存 key 的一部分
+	frameFilter   atomic.Pointer[FrameFilter] // 全局的 FrameFilter, 见 SetFrameFilter
+)
+
+// FrameFilter 从调用栈中去掉匹配的 frame, 如 runtime.*、net/http 的内部函数或者自己的中间件;
+// 过滤结果和调用栈一起缓存, 因此同一个调用点只会过滤一次.
+// 与 ZerologStackWithSkips 不同, 传给 SkipFrame 的是完整的函数名(含 import path)和完整的文件路径:
+//
+//	errors.SetFrameFilter(errors.NewFrameFilter(
+//		errors.SkipFuncPrefix("runtime."),
+//		errors.SkipFuncPrefix("net/http."),
+//	))
+type FrameFilter struct {
+	id    uintptr
+	skips []SkipFrame
+}
+
+// NewFrameFilter 生成 FrameFilter, 任一 SkipFrame 返回 true 的 frame 都会被去掉;
+// FrameFilter 应当在初始化时生成并复用, 每次生成都会产生新的缓存
+func NewFrameFilter(skips ...SkipFrame) *FrameFilter {
+	return &FrameFilter{
+		id:    atomic.AddUintptr(&frameFilterID, 1),
+		skips: skips,
+	}
+}
+
+// SetFrameFilter 设置全局的 FrameFilter, 对之后的 NewCode、CallersSkip 生效; f 为 nil 时取消过滤
+func SetFrameFilter(f *FrameFilter) {
+	frameFilter.Store(f)
+}
+
+// GetFrameFilter 返回全局的 FrameFilter, 未设置时为 nil
+func GetFrameFilter() *FrameFilter {
+	return frameFilter.Load()
+}
+
+func (f *FrameFilter) skip(fr *runtime.Frame) bool {
+	if f == nil {
+		return false
+	}
+	for _, skip := range f.skips {
+		if skip(fr.Function, fr.File, fr.Line) {
+			return true
+		}
+	}
+	return false
+}
+
+// stackKey 在 pcs[:n] 之后追加路径格式、过滤器和 skip, 作为 cacheStack、cacheCallers 的 key;
+// pcs 的长度至少为 n+3. 设置了过滤器时 skip 在过滤之前生效, 因此 skip 也是 key 的一部分
+func stackKey(pcs []uintptr, n int, mode PathMode, f *FrameFilter, skip int) []uintptr {
+	pcs[n] = uintptr(mode)
+	if f == nil {
+		return pcs[:n+1]
+	}
+	pcs[n+1], pcs[n+2] = f.id, uintptr(skip)
+	return pcs[:n+3]
+}
+
+// skipPCs 设置了过滤器时, 在过滤之前去掉 skip 层调用栈
+func skipPCs(pcs []uintptr, f *FrameFilter, skip int) []uintptr {
+	if f == nil || skip <= 0 {
+		return pcs
+	}
+	if skip >= len(pcs) {
+		return nil
+	}
+	return pcs[skip:]
+}
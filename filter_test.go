@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFilter = NewFrameFilter(SkipFuncPrefix("github.com/lxt1045/errors.filterHelper"))
+
+func filterHelper(f func() *Code) *Code {
+	return f()
+}
+
+func filterHelperCallers(f func() []caller) []caller {
+	return f()
+}
+
+func hasHelper(stack []string) bool {
+	for _, s := range stack {
+		if strings.Contains(s, "filterHelper") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFrameFilter(t *testing.T) {
+	newCode := func() *Code {
+		return NewCode(1, errCode, errMsg)
+	}
+
+	t.Run("global", func(t *testing.T) {
+		e := filterHelper(newCode)
+		assert.True(t, hasHelper(e.Stack()))
+
+		SetFrameFilter(testFilter)
+		defer SetFrameFilter(nil)
+		assert.Equal(t, testFilter, GetFrameFilter())
+
+		e = filterHelper(newCode)
+		assert.False(t, hasHelper(e.Stack()))
+		assert.True(t, strings.HasSuffix(e.Stack()[0], "TestFrameFilter.func2"))
+		assert.Equal(t, len(e.Stack()), len(e.StackPCs()))
+		assert.False(t, strings.Contains(e.Error(), "filterHelper"))
+		bs, _ := e.MarshalJSON()
+		assert.False(t, strings.Contains(string(bs), "filterHelper"))
+		assert.False(t, strings.Contains(string(MarshalJSON2(e)), "filterHelper"))
+
+		SetFrameFilter(nil)
+		e = filterHelper(newCode)
+		assert.True(t, hasHelper(e.Stack()))
+	})
+
+	t.Run("per-call", func(t *testing.T) {
+		e := filterHelper(func() *Code {
+			return testFilter.NewCode(0, errCode, errMsg)
+		})
+		assert.False(t, hasHelper(e.Stack()))
+		assert.True(t, strings.HasSuffix(e.Stack()[0], "TestFrameFilter.func3.1"))
+
+		// skip 在过滤之前生效
+		e = filterHelper(func() *Code {
+			return testFilter.NewCode(1, errCode, errMsg)
+		})
+		assert.True(t, strings.HasSuffix(e.Stack()[0], "TestFrameFilter.func3"))
+	})
+
+	t.Run("CallersSkip", func(t *testing.T) {
+		cs := filterHelperCallers(func() []caller { return CallersSkip(1) })
+		assert.Equal(t, "errors.filterHelperCallers", cs[0].Func)
+
+		cs = filterHelperCallers(func() []caller { return testFilter.CallersSkip(1) })
+		assert.Equal(t, "errors.TestFrameFilter.func4", cs[0].Func)
+
+		SetFrameFilter(NewFrameFilter(SkipFuncPrefix("github.com/lxt1045/errors.filterHelperCallers")))
+		defer SetFrameFilter(nil)
+		cs = filterHelperCallers(func() []caller { return CallersSkip(0) })
+		assert.True(t, strings.HasPrefix(cs[0].Func, "errors.TestFrameFilter.func4."))
+		assert.Equal(t, "errors.TestFrameFilter.func4", cs[1].Func)
+	})
+}
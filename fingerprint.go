@@ -0,0 +1,206 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// cacheSite 缓存每个 pc 对应的 "函数名:文件名:行号" 的 hash, 供 Fingerprint 使用
+var cacheSite = RCUCache[uintptr, uint64]{
+	New: func(pc uintptr) uint64 {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		return siteHash(f.Function, f.File, f.Line)
+	},
+}
+
+// siteHash 使用函数名、文件名(不含目录)和行号区分同一函数内的不同调用位置;
+// 不使用 pc, 使得重新编译、修改其它文件后结果不变
+func siteHash(fn, file string, line int) uint64 {
+	d := xxhash.New()
+	_, _ = d.WriteString(fn)
+	_, _ = d.WriteString(":")
+	_, _ = d.WriteString(filepath.Base(file))
+	_, _ = d.WriteString(":")
+	_, _ = d.WriteString(strconv.Itoa(line))
+	return d.Sum64()
+}
+
+type sentinel struct {
+	err  error
+	text string
+}
+
+// fpSentinels 是 Fingerprint 按值区分的哨兵 error 白名单, 其它非本包生成的 error 只使用类型;
+// 写时复制, 读取时不加锁
+var (
+	fpSentinels   atomic.Pointer[[]sentinel]
+	fpSentinelsMu sync.Mutex
+)
+
+func init() {
+	RegisterSentinel(io.EOF, io.ErrUnexpectedEOF, context.Canceled, context.DeadlineExceeded)
+}
+
+// RegisterSentinel 把 errs 加入哨兵 error 白名单, Fingerprint 会按 Error() 区分白名单中的 error;
+// 不在白名单中的非本包 error 只按类型计算, 避免 Error() 中的 id 等变量使指纹发散
+func RegisterSentinel(errs ...error) {
+	fpSentinelsMu.Lock()
+	defer fpSentinelsMu.Unlock()
+	var ss []sentinel
+	if p := fpSentinels.Load(); p != nil {
+		ss = append(ss, *p...)
+	}
+	for _, err := range errs {
+		if err == nil || !reflect.TypeOf(err).Comparable() {
+			continue
+		}
+		if _, ok := findSentinel(ss, err); !ok {
+			ss = append(ss, sentinel{err: err, text: err.Error()})
+		}
+	}
+	fpSentinels.Store(&ss)
+}
+
+func sentinelText(err error) (text string, ok bool) {
+	if p := fpSentinels.Load(); p != nil {
+		return findSentinel(*p, err)
+	}
+	return
+}
+
+func findSentinel(ss []sentinel, err error) (text string, ok bool) {
+	t := reflect.TypeOf(err)
+	for _, s := range ss {
+		if reflect.TypeOf(s.err) == t && sameError(s.err, err) {
+			return s.text, true
+		}
+	}
+	return
+}
+
+// sameError 即 a == b; 可比较的类型中也可能含有值为 slice、map 的 interface 字段,
+// 此时 == 会 panic, 这里视为不相等
+func sameError(a, b error) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+const (
+	fpCode byte = iota + 1
+	fpWrapper
+	fpJoin
+	fpForeign
+	fpBranch
+)
+
+// Fingerprint 返回 err 的指纹, 用于告警去重、统计不同的失败类型:
+// 错误码、Code/Join/WrapStack 的调用栈、Wrap 的调用位置及错误链的结构相同时指纹相同;
+// 调用栈按 "函数名:文件名:行号" 计算, 不包含 pc 和 msg, 因此跨编译版本保持稳定;
+// ParseJSON 等还原的没有 pc 的调用栈按其字符串计算;
+// 非本包生成的 error 只使用其类型, RegisterSentinel 白名单中的哨兵 error 还会使用 Error() 区分。
+// err 为 nil 时返回 0
+func Fingerprint(err error) uint64 {
+	if err == nil {
+		return 0
+	}
+	var buf [256]byte
+	bs := buf[:0]
+	walk(err, 0, func(err error, depth int) bool {
+		bs = appendFingerprint(bs, err, depth)
+		return true
+	})
+	return xxhash.Sum64(bs)
+}
+
+func appendFingerprint(bs []byte, err error, depth int) []byte {
+	if depth > 0 {
+		bs = append(bs, fpBranch)
+		bs = binary.AppendUvarint(bs, uint64(depth))
+	}
+	switch e := err.(type) {
+	case *Code:
+		bs = append(bs, fpCode)
+		bs = binary.AppendVarint(bs, int64(e.code))
+		bs = appendStackFingerprint(bs, e.StackPCs(), e.Stack())
+	case *wrapper:
+		bs = append(bs, fpWrapper)
+		if e.code != nil {
+			bs = binary.AppendVarint(bs, int64(e.code.code))
+		}
+		switch {
+		case e.cache != nil:
+			bs = appendStackFingerprint(bs, e.StackPCs(), e.Stack())
+		case e.parsed != nil:
+			bs = binary.LittleEndian.AppendUint64(bs, xxhash.Sum64String(e.parsed.stack))
+		default:
+			bs = binary.LittleEndian.AppendUint64(bs, cacheSite.Get(e.pc[0]))
+		}
+	case *joinError:
+		bs = append(bs, fpJoin)
+		var pcs []uintptr
+		if e.cache != nil && len(e.cache.pcs) > e.skip {
+			pcs = e.cache.pcs[e.skip:]
+		}
+		bs = appendStackFingerprint(bs, pcs, e.Stack())
+	default:
+		bs = append(bs, fpForeign)
+		bs = append(bs, reflect.TypeOf(err).String()...)
+		if text, ok := sentinelText(err); ok {
+			bs = append(bs, 0)
+			bs = append(bs, text...)
+		}
+		bs = append(bs, 0)
+	}
+	return bs
+}
+
+// appendStackFingerprint 优先使用 pcs, 没有 pc 时使用格式化后的 stack
+func appendStackFingerprint(bs []byte, pcs []uintptr, stack []string) []byte {
+	if len(pcs) > 0 {
+		bs = binary.AppendUvarint(bs, uint64(len(pcs)))
+		for _, pc := range pcs {
+			bs = binary.LittleEndian.AppendUint64(bs, cacheSite.Get(pc))
+		}
+		return bs
+	}
+	bs = binary.AppendUvarint(bs, uint64(len(stack)))
+	for _, s := range stack {
+		bs = binary.LittleEndian.AppendUint64(bs, xxhash.Sum64String(s))
+	}
+	return bs
+}
@@ -0,0 +1,95 @@
+package errors
+
+import (
+	stderrs "errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	newErr := func(code int, msg string) error { return NewCode(0, code, msg) }
+	wrap := func(err error) error { return Wrap(err, errTrace) }
+	wrap2 := func(err error) error { return Wrap(err, errTrace) }
+
+	t.Run("same site", func(t *testing.T) {
+		var fps []uint64
+		for i := 0; i < 3; i++ {
+			fps = append(fps, Fingerprint(wrap(newErr(errCode, fmt.Sprintf("msg %d", i)))))
+		}
+		assert.NotZero(t, fps[0])
+		assert.Equal(t, fps[0], fps[1])
+		assert.Equal(t, fps[0], fps[2])
+	})
+
+	t.Run("different", func(t *testing.T) {
+		base := Fingerprint(wrap(newErr(errCode, errMsg)))
+		assert.NotEqual(t, base, Fingerprint(wrap(newErr(errCode+1, errMsg))), "code")
+		assert.NotEqual(t, base, Fingerprint(wrap2(newErr(errCode, errMsg))), "wrapper site")
+		assert.NotEqual(t, base, Fingerprint(newErr(errCode, errMsg)), "wrapper count")
+		assert.NotEqual(t, base, Fingerprint(wrap(NewCode(0, errCode, errMsg))), "stack")
+	})
+
+	t.Run("same func", func(t *testing.T) {
+		// 同一函数内的两个调用位置
+		sites := func(err error) (a, b error) {
+			a = Wrap(err, errTrace)
+			b = Wrap(err, errTrace)
+			return
+		}
+		a, b := sites(io.EOF)
+		assert.NotEqual(t, Fingerprint(a), Fingerprint(b), "wrapper")
+		codes := func() (a, b error) {
+			a = NewCode(0, errCode, errMsg)
+			b = NewCode(0, errCode, errMsg)
+			return
+		}
+		a, b = codes()
+		assert.NotEqual(t, Fingerprint(a), Fingerprint(b), "code")
+	})
+
+	t.Run("foreign", func(t *testing.T) {
+		assert.Zero(t, Fingerprint(nil))
+		assert.Equal(t, Fingerprint(io.EOF), Fingerprint(io.EOF))
+		assert.NotEqual(t, Fingerprint(io.EOF), Fingerprint(io.ErrUnexpectedEOF))
+		assert.Equal(t, Fingerprint(stderrs.New("id 1")), Fingerprint(stderrs.New("id 2")), "not sentinel")
+		assert.NotEqual(t, Fingerprint(io.EOF), Fingerprint(stderrs.New("EOF")), "not sentinel")
+
+		sentinel := stderrs.New("sentinel")
+		base := Fingerprint(sentinel)
+		RegisterSentinel(sentinel)
+		assert.NotEqual(t, base, Fingerprint(sentinel))
+		assert.NotEqual(t, Fingerprint(sentinel), Fingerprint(stderrs.New("sentinel")))
+		assert.Equal(t,
+			Fingerprint(fmt.Errorf("id %d: %w", 1, io.EOF)),
+			Fingerprint(fmt.Errorf("id %d: %w", 2, io.EOF)))
+	})
+
+	t.Run("unhashable", func(t *testing.T) {
+		RegisterSentinel(valueErr{v: 1})
+		assert.NotPanics(t, func() {
+			Fingerprint(valueErr{v: []int{1}})
+			Fingerprint(valueErr{v: map[string]int{}})
+			Fingerprint(Wrap(valueErr{v: []int{1}}, errTrace))
+		})
+		assert.NotEqual(t, Fingerprint(valueErr{v: 1}), Fingerprint(valueErr{v: 2}))
+		assert.Equal(t, Fingerprint(valueErr{v: []int{1}}), Fingerprint(valueErr{v: 2}))
+	})
+
+	t.Run("join", func(t *testing.T) {
+		join := func(errs ...error) error { return Join(errs...) }
+		var fps []uint64
+		for _, msg := range []string{"a", "b"} {
+			fps = append(fps, Fingerprint(join(newErr(errCode, msg), io.EOF)))
+		}
+		assert.Equal(t, fps[0], fps[1])
+		assert.NotEqual(t, fps[0], Fingerprint(join(io.EOF, newErr(errCode, "b"))))
+	})
+}
+
+// valueErr 是可比较的值类型 error, 但 v 中存放 slice、map 时 == 会 panic
+type valueErr struct{ v interface{} }
+
+func (e valueErr) Error() string { return fmt.Sprint(e.v) }
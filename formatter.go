@@ -25,6 +25,7 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strconv"
@@ -52,74 +53,147 @@ func MarshalJSON(err error) (bs []byte) {
 		return []byte(`null`)
 	}
 	buf := &writeBuffer{}
-	marshalJSON(2, buf, err)
-	bs = buf.Bytes()
-	if len(bs) == 0 {
-		return []byte(`null`)
+	writeJSON(buf, err, getJSONSchema())
+	return buf.Bytes()
+}
+
+// AppendJSON 将 MarshalJSON 的结果追加到 dst 后返回, dst 容量足够时不会分配内存
+func AppendJSON(dst []byte, err error) []byte {
+	buf := writeBuffer{buf: dst}
+	writeJSON(&buf, err, getJSONSchema())
+	return buf.buf
+}
+
+// WriteJSON 将 MarshalJSON 的结果写入 w, 序列化使用的 buffer 来自 pool
+func WriteJSON(w io.Writer, err error) (n int, e error) {
+	buf := getBuffer()
+	writeJSON(buf, err, getJSONSchema())
+	n, e = w.Write(buf.buf)
+	putBuffer(buf)
+	return
+}
+
+// writeJSON 将 MarshalJSON 的结果追加到 buf 中
+func writeJSON(buf *writeBuffer, err error, s *jsonSchema) {
+	start := len(buf.buf)
+	marshalJSON(len(s.close), buf, err, s)
+	if len(buf.buf) == start {
+		buf.WriteString(`null`)
+		return
 	}
-	if bs[len(bs)-1] == ',' {
-		bs = bs[:len(bs)-1]
+	if buf.buf[len(buf.buf)-1] == ',' {
+		buf.buf = buf.buf[:len(buf.buf)-1]
 	}
-	return append(bs, `]}`...)
+	buf.WriteString(s.close)
 }
 
 // marshalJSON 递归 Unwrap 并序列化为 JSON 格式
-func marshalJSON(size int, buf *writeBuffer, err error) {
+func marshalJSON(size int, buf *writeBuffer, err error, s *jsonSchema) {
 	switch e := err.(type) {
 	//如果将 *wrapper 和 *Code 合成一个 interface{} 分支, 将导致性能退化
 	case *Code:
 		cache := e.fmt()
-		buf.Grow(size + cache.jsonSize() + len(`{"cause":,"wrapper":[`))
-		buf.WriteString(`{"cause":`)
-		cache.json(buf)
-		buf.WriteString(`,"wrapper":[`)
+		if e.err != nil {
+			// 带 cause 的 Code 与 wrapper 一样, 作为一层追加在 cause 链之后
+			needSize := cache.jsonSize(s) + 1
+			marshalJSON(size+needSize, buf, e.err, s)
+			cache.json(buf, s)
+			buf.WriteByte(',')
+			return
+		}
+		buf.Grow(size + cache.jsonSize(s) + len(s.open) + len(s.sep))
+		buf.WriteString(s.open)
+		cache.json(buf, s)
+		buf.WriteString(s.sep)
 	case *wrapper:
 		cache := e.fmt()
-		needSize := cache.jsonSize() + 1
-		marshalJSON(size+needSize, buf, e.Unwrap())
-		cache.json(buf)
+		if e.err == nil {
+			// NewLine 生成的 wrapper 没有 cause, 自身作为 cause
+			buf.Grow(size + cache.jsonSize(s) + len(s.open) + len(s.sep))
+			buf.WriteString(s.open)
+			cache.json(buf, s)
+			buf.WriteString(s.sep)
+			return
+		}
+		needSize := cache.jsonSize(s) + 1
+		marshalJSON(size+needSize, buf, e.Unwrap(), s)
+		cache.json(buf, s)
 		buf.WriteByte(',')
 		return
+	case multiError:
+		buf.Grow(size + len(s.open) + len(s.sep))
+		buf.WriteString(s.open)
+		cs, skip := multiStack(e)
+		buf.buf = appendJoinJSON(buf.buf, e.Unwrap(), cs, skip, false, s)
+		buf.WriteString(s.sep)
 	case fmt.Formatter:
-		cache := fmt.Sprintf("%+v", err)
-		cacheSize, escape := countEscape(cache)
-		buf.Grow(size + cacheSize + len(`{"cause":"","wrapper":[`))
-		buf.WriteString(`{"cause":"`)
-		if !escape {
-			buf.WriteString(cache)
-		} else {
-			buf.WriteEscape(cache)
-		}
-		buf.WriteString(`","wrapper":[`)
+		writeJSONCause(size, buf, formatterText(err), s)
 	default:
 		if err == nil {
 			buf.Grow(size)
 			return
 		}
-		cache := e.Error()
-		cacheSize, escape := countEscape(cache)
-		buf.Grow(size + cacheSize + len(`{"cause":"","wrapper":[`))
-		buf.WriteString(`{"cause":"`)
-		if !escape {
-			buf.WriteString(cache)
-		} else {
-			buf.WriteEscape(cache)
-		}
-		buf.WriteString(`","wrapper":[`)
+		writeJSONCause(size, buf, causeText(e), s)
 	}
 }
 
+// writeJSONCause 把非本包的 error 信息作为 cause 写入 buf
+func writeJSONCause(size int, buf *writeBuffer, cause string, s *jsonSchema) {
+	causeSize, escape := countEscape(cause)
+	buf.Grow(size + causeSize + len(s.open) + len(s.strOpen) + len(s.strClose) + len(s.sep))
+	buf.WriteString(s.open)
+	buf.WriteString(s.strOpen)
+	if !escape {
+		buf.WriteString(cause)
+	} else {
+		buf.WriteEscape(cause)
+	}
+	buf.WriteString(s.strClose)
+	buf.WriteString(s.sep)
+}
+
+// MarshalText 将 err 序列化为文本格式, 格式由 SetTextMode 决定
 func MarshalText(err error) (bs []byte) {
 	buf := &writeBuffer{}
-	marshalText(0, buf, err)
+	writeText(buf, err)
 	return buf.Bytes()
 }
 
+// AppendText 将 MarshalText 的结果追加到 dst 后返回, dst 容量足够时不会分配内存
+func AppendText(dst []byte, err error) []byte {
+	buf := writeBuffer{buf: dst}
+	writeText(&buf, err)
+	return buf.buf
+}
+
+// WriteText 将 MarshalText 的结果写入 w, 序列化使用的 buffer 来自 pool
+func WriteText(w io.Writer, err error) (n int, e error) {
+	buf := getBuffer()
+	writeText(buf, err)
+	n, e = w.Write(buf.buf)
+	putBuffer(buf)
+	return
+}
+
+func writeText(buf *writeBuffer, err error) {
+	if GetTextMode() == TextLogfmt {
+		buf.buf = appendLogfmt(buf.buf, err)
+		return
+	}
+	marshalText(0, buf, err)
+}
+
 func marshalText(size int, buf *writeBuffer, err error) {
 	switch e := err.(type) {
 	case *Code:
 		cache := e.fmt()
 		needSize := cache.textSize()
+		if e.err != nil {
+			marshalText(size+needSize+1, buf, e.err)
+			buf.WriteByte('\n')
+			cache.text(buf)
+			return
+		}
 		buf.Grow(size + needSize)
 		cache.text(buf)
 	case *wrapper:
@@ -128,8 +202,12 @@ func marshalText(size int, buf *writeBuffer, err error) {
 		marshalText(size+needSize, buf, errors.Unwrap(err))
 		buf.WriteByte('\n')
 		cache.text(buf)
+	case multiError:
+		buf.Grow(size)
+		cs, skip := multiStack(e)
+		writeJoinText(buf, e.Unwrap(), cs, skip)
 	case fmt.Formatter:
-		cache := fmt.Sprintf("%+v", err)
+		cache := formatterText(err)
 		buf.Grow(size + len(cache) + 1)
 		buf.WriteString(cache)
 		buf.WriteByte(';')
@@ -138,7 +216,7 @@ func marshalText(size int, buf *writeBuffer, err error) {
 			buf.Grow(size)
 			return
 		}
-		cache := e.Error()
+		cache := causeText(e)
 		buf.Grow(size + len(cache) + 1)
 		buf.WriteString(cache)
 		buf.WriteByte(';')
@@ -197,6 +275,11 @@ func toCaller1(f runtime.Frame) caller { // nolint:gocritic
 }
 
 func toCaller(f runtime.Frame) caller { // nolint:gocritic
+	return toCallerMode(f, GetPathMode())
+}
+
+// toCallerMode 按 mode 格式化文件路径; 函数名只保留最后一段 import path
+func toCallerMode(f runtime.Frame, mode PathMode) caller { // nolint:gocritic
 	funcName, file, line := f.Function, f.File, f.Line
 
 	i := strings.LastIndexByte(funcName, os.PathSeparator)
@@ -206,20 +289,7 @@ func toCaller(f runtime.Frame) caller { // nolint:gocritic
 	if i >= 0 {
 		funcName = funcName[i+1:]
 	}
-	i = strings.LastIndex(file, pathSeparator)
-	if !samePathSeparator && i < 0 {
-		i = strings.LastIndexByte(file, '/')
-	}
-	if i >= 0 {
-		j := strings.LastIndex(file[:i], pathSeparator)
-		if !samePathSeparator && j < 0 {
-			j = strings.LastIndexByte(file[:i], '/')
-		}
-		if j > 0 {
-			i = j
-		}
-		file = file[i+1:]
-	}
+	file = trimPath(file, f.Function, mode)
 
 	var fileLine string
 	if file != "" {
@@ -264,82 +334,86 @@ func MarshalJSON2(err error) (bs []byte) {
 	if err == nil {
 		return []byte(`null`)
 	}
-	bs = marshalJSON2(1, bs, err)
-	if len(bs) == 0 {
-		return []byte(`null`)
+	return appendJSON2(bs, err, getJSONSchema())
+}
+
+// appendJSON2 将 MarshalJSON2 的结果追加到 bs 后返回
+func appendJSON2(bs []byte, err error, s *jsonSchema) []byte {
+	start := len(bs)
+	bs = marshalJSON2(len(s.close), bs, err, s)
+	if len(bs) == start {
+		return append(bs, `null`...)
 	}
-	// 末尾预期为 ',' (wrapper 叠加) 或 '[' (无 wrapper); 将其替换为 ']'
-	last := bs[len(bs)-1]
-	if last == ',' || last == '[' {
-		bs[len(bs)-1] = ']'
-	} else {
-		bs = append(bs, ']')
+	// 末尾为 ',' 时 (wrapper 叠加或 Flat) 去掉
+	if bs[len(bs)-1] == ',' {
+		bs = bs[:len(bs)-1]
 	}
-	bs = append(bs, '}')
-
-	return
+	return append(bs, s.close...)
 }
 
-func marshalJSON2(size int, bs []byte, err error) []byte {
+func marshalJSON2(size int, bs []byte, err error, s *jsonSchema) []byte {
 	errInner := errors.Unwrap(err)
 	switch e := err.(type) {
 	case *wrapper:
 		cache := e.fmt()
 		if errInner != nil {
-			needSize := cache.jsonSize() + 1
-			bs = marshalJSON2(size+needSize, bs, errInner)
-			bs = cache.json2(bs)
+			needSize := cache.jsonSize(s) + 1
+			bs = marshalJSON2(size+needSize, bs, errInner, s)
+			bs = cache.json2(bs, s)
 			bs = append(bs, ',')
 			return bs
 		}
-		bs = tryGrow(bs, size+cache.jsonSize()+9+12)
-		bs = append(bs, `{"cause":`...)
-		bs = cache.json2(bs)
-		bs = append(bs, `,"wrapper":[`...)
+		bs = tryGrow(bs, size+cache.jsonSize(s)+len(s.open)+len(s.sep))
+		bs = append(bs, s.open...)
+		bs = cache.json2(bs, s)
+		bs = append(bs, s.sep...)
 	case *Code:
 		cache := e.fmt()
 		if errInner != nil {
-			needSize := cache.jsonSize() + 1
-			bs = marshalJSON2(size+needSize, bs, errInner)
-			bs = cache.json2(bs)
+			needSize := cache.jsonSize(s) + 1
+			bs = marshalJSON2(size+needSize, bs, errInner, s)
+			bs = cache.json2(bs, s)
 			bs = append(bs, ',')
 			return bs
 		}
-		bs = tryGrow(bs, size+cache.jsonSize()+9+12)
-		bs = append(bs, `{"cause":`...)
-		bs = cache.json2(bs)
-		bs = append(bs, `,"wrapper":[`...)
+		bs = tryGrow(bs, size+cache.jsonSize(s)+len(s.open)+len(s.sep))
+		bs = append(bs, s.open...)
+		bs = cache.json2(bs, s)
+		bs = append(bs, s.sep...)
+	case multiError:
+		bs = tryGrow(bs, size+len(s.open)+len(s.sep))
+		bs = append(bs, s.open...)
+		cs, skip := multiStack(e)
+		bs = appendJoinJSON(bs, e.Unwrap(), cs, skip, true, s)
+		bs = append(bs, s.sep...)
 	case fmt.Formatter:
-		cache := fmt.Sprintf("%+v", err)
-		if errInner != nil {
-			needSize := len(cache) + 10 + 3
-			bs = marshalJSON2(size+needSize, bs, errInner)
-			bs = append(bs, `{"trace":"`...)
-			bs = append(bs, cache...)
-			bs = append(bs, `"},`...)
-			return bs
-		}
-		bs = tryGrow(bs, size+len(cache)+10+13)
-		bs = append(bs, `{"cause":"`...)
-		bs = append(bs, cache...)
-		bs = append(bs, `","wrapper":[`...)
+		bs = appendJSONLayer(size, bs, formatterText(err), errInner, s)
 	default:
-		cache := e.Error()
-		if errInner != nil {
-			needSize := len(cache) + 10 + 3
-			bs = marshalJSON2(size+needSize, bs, errInner)
-			bs = append(bs, `{"trace":"`...)
-			bs = append(bs, cache...)
-			bs = append(bs, `"},`...)
-			return bs
-		}
-		bs = tryGrow(bs, size+len(cache)+10+13)
-		bs = append(bs, `{"cause":"`...)
-		bs = append(bs, cache...)
-		bs = append(bs, `","wrapper":[`...)
+		bs = appendJSONLayer(size, bs, causeText(e), errInner, s)
 	}
 	return bs
 }
+
+// appendJSONLayer 把非本包的 error 信息追加到 bs: 有 inner 时作为一层 {"trace":"..."}, 否则作为 cause
+func appendJSONLayer(size int, bs []byte, msg string, inner error, s *jsonSchema) []byte {
+	if inner != nil {
+		needSize := len(msg) + len(`{""},`) + len(s.trace)
+		bs = marshalJSON2(size+needSize, bs, inner, s)
+		bs = append(bs, '{')
+		bs = append(bs, s.trace...)
+		bs = append(bs, '"')
+		bs = appendEscape(bs, msg)
+		bs = append(bs, `"},`...)
+		return bs
+	}
+	bs = tryGrow(bs, size+len(msg)+len(s.open)+len(s.strOpen)+len(s.strClose)+len(s.sep))
+	bs = append(bs, s.open...)
+	bs = append(bs, s.strOpen...)
+	bs = appendEscape(bs, msg)
+	bs = append(bs, s.strClose...)
+	bs = append(bs, s.sep...)
+	return bs
+}
 func tryGrow(bs []byte, l int) []byte {
 	if cap(bs) < l {
 		bs2 := make([]byte, len(bs), l+len(bs))
@@ -350,49 +424,58 @@ func tryGrow(bs []byte, l int) []byte {
 	return bs
 }
 
-func (f *fmtCode) json2(bs []byte) []byte {
-	bs = append(bs, `{"code":`...)
-	bs = append(bs, f.code...)
-	bs = append(bs, `,"msg":"`...)
-	if !f.msgEscape {
-		bs = append(bs, f.msg...)
-	} else {
-		bs = appendEscape(bs, f.msg)
-	}
-	bs = append(bs, '"')
-	if len(f.stack) > f.skip {
-		bs = append(bs, `,"stack":[`...)
-		for i, str := range f.stack[f.skip:] {
-			if i != 0 {
-				bs = append(bs, ',')
-			}
-			bs = append(bs, '"')
-			if f.attr&(1<<i) == 0 {
-				bs = append(bs, str...)
-			} else {
-				bs = appendEscape(bs, str)
-			}
-			bs = append(bs, '"')
+func (f *fmtCode) json2(bs []byte, s *jsonSchema) []byte {
+	bs = append(bs, '{')
+	bs = append(bs, s.code...)
+	bs = strconv.AppendInt(bs, int64(f.code), 10)
+	if s.keep(f.msg) {
+		bs = append(bs, ',')
+		bs = append(bs, s.msg...)
+		bs = append(bs, '"')
+		if !f.msgEscape {
+			bs = append(bs, f.msg...)
+		} else {
+			bs = appendEscape(bs, f.msg)
 		}
-		bs = append(bs, ']')
+		bs = append(bs, '"')
+	}
+	bs = append(bs, f.fieldsBuf...)
+	if f.callers != nil && len(f.stack) > f.skip {
+		bs = s.appendStack(bs, f.callers, f.skip, len(f.stack))
 	}
 	bs = append(bs, '}')
 	return bs
 }
 
-func (f *fmtWrapper) json2(bs []byte) []byte {
-	bs = append(bs, `{"trace":"`...)
-	if !f.traceEscape {
-		bs = append(bs, f.trace...)
-	} else {
-		bs = appendEscape(bs, f.trace)
+func (f *fmtWrapper) json2(bs []byte, s *jsonSchema) []byte {
+	bs = append(bs, '{')
+	if f.code != "" {
+		bs = append(bs, s.code...)
+		bs = append(bs, f.code...)
+		bs = append(bs, ',')
+	}
+	if s.keep(f.trace) {
+		bs = append(bs, s.trace...)
+		bs = append(bs, '"')
+		if !f.traceEscape {
+			bs = append(bs, f.trace...)
+		} else {
+			bs = appendEscape(bs, f.trace)
+		}
+		bs = append(bs, `",`...)
 	}
-	bs = append(bs, `","caller":"`...)
+	bs = append(bs, s.caller...)
+	bs = append(bs, '"')
 	if (f.attr & 1) == 0 {
 		bs = append(bs, f.stack...)
 	} else {
 		bs = appendEscape(bs, f.stack)
 	}
-	bs = append(bs, `"}`...)
+	bs = append(bs, '"')
+	if f.callers != nil {
+		bs = s.appendStack(bs, f.callers, f.from, f.to)
+	}
+	bs = append(bs, f.fieldsBuf...)
+	bs = append(bs, '}')
 	return bs
 }
@@ -6,10 +6,12 @@ import (
 	"errors"
 	stderrs "errors"
 	"fmt"
+	"io"
 	"testing"
 	"unicode/utf8"
 
 	pkgerrs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCountEscape(t *testing.T) {
@@ -210,6 +212,34 @@ go test -benchmem -run=^$ -bench "^(BenchmarkMarshal)$" github.com/lxt1045/error
 go tool pprof ./errors.test mem.prof
 web
 */
+func TestAppendJSON(t *testing.T) {
+	var err error = NewCode(0, errCode, errMsg)
+	err = Wrap(err, errTrace)
+
+	prefix := []byte(`{"err":`)
+	bs := AppendJSON(prefix, err)
+	assert.Equal(t, string(prefix)+string(MarshalJSON(err)), string(bs))
+	assert.Equal(t, `null`, string(AppendJSON(nil, nil)))
+	assert.Equal(t, string(MarshalText(err)), string(AppendText(nil, err)))
+
+	buf := &bytes.Buffer{}
+	n, e := WriteJSON(buf, err)
+	assert.Nil(t, e)
+	assert.Equal(t, buf.Len(), n)
+	assert.Equal(t, string(MarshalJSON(err)), buf.String())
+
+	buf.Reset()
+	_, e = WriteText(buf, err)
+	assert.Nil(t, e)
+	assert.Equal(t, string(MarshalText(err)), buf.String())
+
+	dst := make([]byte, 0, 4096)
+	allocs := testing.AllocsPerRun(100, func() {
+		dst = AppendJSON(dst[:0], err)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
 func BenchmarkMarshal(b *testing.B) {
 	var err error = NewCode(0, errCode, errMsg+"awesrdtfghjklsajghfdjkshdhgagdkaskdhakhkj")
 	for i := 0; i < 0; i++ {
@@ -263,6 +293,32 @@ func BenchmarkMarshal(b *testing.B) {
 			}
 			b.StopTimer()
 		})
+		b.Run("AppendJSON", func(b *testing.B) {
+			bs := make([]byte, 0, 4096)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bs = AppendJSON(bs[:0], err)
+			}
+			b.StopTimer()
+		})
+		b.Run("AppendText", func(b *testing.B) {
+			bs := make([]byte, 0, 4096)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bs = AppendText(bs[:0], err)
+			}
+			b.StopTimer()
+		})
+		b.Run("WriteJSON", func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				WriteJSON(io.Discard, err)
+			}
+			b.StopTimer()
+		})
 	}
 }
 func Benchmark_JSON(b *testing.B) {
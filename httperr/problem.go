@@ -0,0 +1,256 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package httperr 把包含 *errors.Code 的 error 转换为 RFC 7807 application/problem+json 响应,
+// 以及在客户端把 problem+json 还原为 *errors.Code
+package httperr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/lxt1045/errors"
+)
+
+const ContentType = "application/problem+json"
+
+var (
+	debug       atomic.Bool
+	typeBaseURI atomic.Pointer[string]
+	service     atomic.Pointer[string]
+
+	// StatusOf 决定错误码对应的 HTTP 状态码, 默认使用错误码注册信息里的 HTTPStatus
+	StatusOf = func(c *errors.Code) int {
+		if info, ok := c.Info(); ok && info.HTTPStatus != 0 {
+			return info.HTTPStatus
+		}
+		return http.StatusInternalServerError
+	}
+)
+
+// SetDebug 为 true 时响应中包含调用栈, 没有错误码的 error 和 panic 的 detail 为原始的错误信息
+func SetDebug(on bool) {
+	debug.Store(on)
+}
+
+// GetDebug 返回 SetDebug 的设置
+func GetDebug() bool {
+	return debug.Load()
+}
+
+// SetTypeBaseURI 非空时, problem 的 type 为 uri + 小写的错误码名称, title 为错误码名称;
+// 否则 type 为 about:blank, title 为 HTTP 状态码的描述
+func SetTypeBaseURI(uri string) {
+	typeBaseURI.Store(&uri)
+}
+
+// GetTypeBaseURI 返回 SetTypeBaseURI 的设置
+func GetTypeBaseURI() string {
+	if p := typeBaseURI.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+// SetService 设置当前服务名, 非空时写入 problem 的 service 字段, 客户端 Decode 时用于标记远端调用栈
+func SetService(name string) {
+	service.Store(&name)
+}
+
+// GetService 返回 SetService 的设置
+func GetService() string {
+	if p := service.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
+
+// Problem RFC 7807 problem details, 扩展了 code 和 stack 字段
+type Problem struct {
+	Type     string   `json:"type"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Detail   string   `json:"detail,omitempty"`
+	Instance string   `json:"instance,omitempty"`
+	Code     int      `json:"code"`
+	Service  string   `json:"service,omitempty"`
+	Stack    []string `json:"stack,omitempty"`
+}
+
+// FromError 用 errors.FindCode 在 err 链上查找 *errors.Code 并生成 Problem; r 可为 nil;
+// 找不到 *errors.Code 时 detail 为 DefaultCode 注册的 Msg 或 HTTP 状态码的描述, 避免把内部的错误信息返回给客户端;
+// err 为 nil 时返回 nil
+func FromError(err error, r *http.Request) *Problem {
+	if err == nil {
+		return nil
+	}
+	isDebug := GetDebug()
+	c, ok := errors.FindCode(err)
+	if !ok {
+		c = errors.AsCode(err)
+	}
+	p := &Problem{
+		Type:    "about:blank",
+		Status:  StatusOf(c),
+		Detail:  c.Msg(),
+		Code:    c.Code(),
+		Service: GetService(),
+	}
+	if !ok {
+		p.Detail = defaultDetail(p.Status)
+		if isDebug {
+			p.Detail = err.Error()
+		}
+	}
+	// RFC 7807 4.2: type 为 about:blank 时 title 应为 HTTP 状态码的描述
+	p.Title = http.StatusText(p.Status)
+	if info, ok := c.Info(); ok && info.Name != "" {
+		if base := GetTypeBaseURI(); base != "" {
+			p.Type = base + strings.ToLower(info.Name)
+			p.Title = info.Name
+		}
+	}
+	p.setInstance(r)
+	if isDebug {
+		p.Stack = c.Stack()
+	}
+	return p
+}
+
+func (p *Problem) setInstance(r *http.Request) {
+	if r != nil && r.URL != nil {
+		p.Instance = r.URL.RequestURI()
+	}
+}
+
+// defaultDetail 返回没有错误码时的 detail
+func defaultDetail(status int) string {
+	if info, ok := errors.Lookup(errors.DefaultCode); ok && info.Msg != "" {
+		return info.Msg
+	}
+	return http.StatusText(status)
+}
+
+// Err 把 Problem 还原为 *errors.Code, 调用栈为服务端返回的 stack
+func (p *Problem) Err() *errors.Code {
+	detail := p.Detail
+	if detail == "" {
+		detail = p.Title
+	}
+	return errors.NewCodeWithStack(p.Code, detail, p.Stack).(*errors.Code)
+}
+
+// Write 以 problem+json 格式写入响应
+func (p *Problem) Write(w http.ResponseWriter) {
+	bs, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(bs) //nolint:errcheck
+}
+
+// WriteError 把 err 转换为 problem+json 写入响应; err 为 nil 时视为没有错误码的内部错误, 返回 500
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	p := FromError(err, r)
+	if p == nil {
+		p = &Problem{
+			Type:    "about:blank",
+			Title:   http.StatusText(http.StatusInternalServerError),
+			Status:  http.StatusInternalServerError,
+			Detail:  defaultDetail(http.StatusInternalServerError),
+			Code:    errors.DefaultCode,
+			Service: GetService(),
+		}
+		p.setInstance(r)
+	}
+	p.Write(w)
+}
+
+// Recover 中间件: 捕获 next 中的 panic, 按 WriteError 的规则返回 500; 只有 Debug 为 true 时 detail 才包含 panic 的内容
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			c := errors.NewCode(0, errors.DefaultCode, "panic: %v", v)
+			if e, ok := v.(error); ok {
+				c = c.WithErr(e)
+			}
+			p := FromError(c, r)
+			if !GetDebug() {
+				p.Detail = defaultDetail(p.Status)
+			}
+			p.Write(w)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Decode 客户端使用: resp 为 problem+json 时返回对应的 *errors.Code, 否则返回 nil, nil; 不会关闭 resp.Body;
+// err 只表示读取或解析 resp.Body 失败, 此时 c 为 nil;
+// 返回的 *errors.Code 的调用栈包含服务端的(标记为 remote)和本地调用 Decode 处的
+func Decode(resp *http.Response) (c *errors.Code, err error) {
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mt != ContentType {
+		return nil, nil
+	}
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("httperr: read problem: %w", err)
+	}
+	p, err := Parse(bs)
+	if err != nil {
+		return nil, fmt.Errorf("httperr: decode problem: %w", err)
+	}
+	service := p.Service
+	if service == "" && resp.Request != nil && resp.Request.URL != nil {
+		service = resp.Request.URL.Host
+	}
+	detail := p.Detail
+	if detail == "" {
+		detail = p.Title
+	}
+	return errors.NewRemoteCode(1, service, p.Code, detail, p.Stack), nil
+}
+
+// Parse 解析 problem+json
+func Parse(bs []byte) (p *Problem, err error) {
+	p = &Problem{}
+	if err = json.Unmarshal(bs, p); err != nil {
+		return nil, err
+	}
+	return
+}
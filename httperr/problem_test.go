@@ -0,0 +1,171 @@
+package httperr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lxt1045/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var errNotFound = errors.DefineCode(errors.CodeInfo{
+	Code:       4049001,
+	Name:       "ORDER_NOT_FOUND",
+	Msg:        "order not found",
+	HTTPStatus: http.StatusNotFound,
+})
+
+func TestWriteError(t *testing.T) {
+	t.Run("code", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/orders/1?x=1", nil)
+		w := httptest.NewRecorder()
+		err := fmt.Errorf("load: %w", errors.Wrap(errNotFound.New("order 1"), "handler"))
+		WriteError(w, r, err)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+		p, err := Parse(w.Body.Bytes())
+		assert.Nil(t, err)
+		assert.Equal(t, &Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(http.StatusNotFound),
+			Status:   http.StatusNotFound,
+			Detail:   "order 1",
+			Instance: "/orders/1?x=1",
+			Code:     4049001,
+		}, p)
+	})
+
+	t.Run("WrapCode", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		err := errors.WrapCode(errors.NewCode(0, 5001, "db timeout"), errNotFound, "load order")
+		WriteError(w, nil, err)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		p, err := Parse(w.Body.Bytes())
+		assert.Nil(t, err)
+		assert.Equal(t, 4049001, p.Code)
+		assert.Equal(t, http.StatusText(http.StatusNotFound), p.Title)
+		assert.Equal(t, "db timeout", p.Detail)
+	})
+
+	t.Run("TypeBaseURI", func(t *testing.T) {
+		SetTypeBaseURI("https://example.com/problems/")
+		defer SetTypeBaseURI("")
+		p := FromError(errNotFound.New("order 1"), nil)
+		assert.Equal(t, "https://example.com/problems/order_not_found", p.Type)
+		assert.Equal(t, "ORDER_NOT_FOUND", p.Title)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		assert.Nil(t, FromError(nil, nil))
+		w := httptest.NewRecorder()
+		WriteError(w, httptest.NewRequest(http.MethodGet, "/a", nil), nil)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		p, err := Parse(w.Body.Bytes())
+		assert.Nil(t, err)
+		assert.Equal(t, "about:blank", p.Type)
+		assert.Equal(t, errors.DefaultCode, p.Code)
+		assert.Equal(t, "/a", p.Instance)
+	})
+
+	t.Run("foreign", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		WriteError(w, nil, fmt.Errorf("boom"))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		m := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &m))
+		assert.Equal(t, http.StatusText(http.StatusInternalServerError), m["detail"])
+		assert.Equal(t, float64(errors.DefaultCode), m["code"])
+	})
+
+	t.Run("foreign-wrap", func(t *testing.T) {
+		err := errors.Wrap(io.EOF, "read body")
+		p := FromError(err, nil)
+		assert.Equal(t, http.StatusText(http.StatusInternalServerError), p.Detail)
+		assert.Nil(t, p.Stack)
+
+		SetDebug(true)
+		defer SetDebug(false)
+		p = FromError(err, nil)
+		assert.Equal(t, err.Error(), p.Detail)
+	})
+}
+
+func TestRecover(t *testing.T) {
+	h := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	p, err := Parse(w.Body.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusText(http.StatusInternalServerError), p.Detail)
+	assert.Nil(t, p.Stack)
+
+	SetDebug(true)
+	defer SetDebug(false)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	p, err = Parse(w.Body.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "panic: oops", p.Detail)
+	assert.True(t, len(p.Stack) > 0)
+}
+
+func TestDecode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, r, errNotFound.New("order 2"))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	c, err := Decode(resp)
+	assert.Nil(t, err)
+	assert.Equal(t, 4049001, c.Code())
+	assert.Equal(t, "order 2", c.Msg())
+	assert.True(t, errors.Is(c, errNotFound))
+}
+
+func TestDecodeRemote(t *testing.T) {
+	SetDebug(true)
+	SetService("order-svc")
+	defer func() { SetDebug(false); SetService("") }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, r, errNotFound.New("order 3"))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	c, err := Decode(resp)
+	assert.Nil(t, err)
+	stack := c.Stack()
+	assert.True(t, len(stack) > 1)
+	service, ok := errors.RemoteService(stack[0])
+	assert.True(t, ok)
+	assert.Equal(t, "order-svc", service)
+	_, ok = errors.RemoteService(stack[len(stack)-1])
+	assert.False(t, ok)
+}
+
+func TestDecodeFailure(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{"))}
+	c, err := Decode(resp)
+	assert.Nil(t, c)
+	assert.Nil(t, err)
+
+	resp.Header.Set("Content-Type", ContentType)
+	c, err = Decode(resp)
+	assert.Nil(t, c)
+	assert.Error(t, err)
+}
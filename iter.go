@@ -0,0 +1,129 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"iter"
+	"strconv"
+)
+
+// LayerKind 错误链上一层的类型
+type LayerKind int8
+
+const (
+	LayerForeign LayerKind = iota // 非本包生成的 error, 如 io.EOF、fmt.Errorf、errors.Join
+	LayerCode                     // *Code
+	LayerWrapper                  // Wrap、WrapCode、WrapStack、NewLine 等生成的 wrapper
+	LayerJoin                     // 本包 Join 生成的 error
+)
+
+func (k LayerKind) String() string {
+	switch k {
+	case LayerForeign:
+		return "foreign"
+	case LayerCode:
+		return "code"
+	case LayerWrapper:
+		return "wrapper"
+	case LayerJoin:
+		return "join"
+	}
+	return "LayerKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Layer 错误链上的一层, 由 Layers 生成
+type Layer struct {
+	Kind  LayerKind
+	Err   error // 这一层本身
+	Depth int   // 所在 multi-unwrap 分支的嵌套层数, 主链为 0
+
+	Msg    string  // Code 的 msg、wrapper 的 trace; foreign 为 Error(), join 为空
+	Code   int     // Code 的错误码或 WrapCode 附加的错误码, 没有时为 DefaultCode
+	Caller string  // wrapper 的调用位置, Code、join 调用栈的第一行; foreign 为空
+	Attrs  []Field // 这一层自身附加的 fields
+}
+
+// All 按深度优先(先序)遍历 err 及其 Unwrap 出的所有 error, 包括 Unwrap() []error 的各个分支
+func All(err error) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		walk(err, 0, func(err error, _ int) bool {
+			return yield(err)
+		})
+	}
+}
+
+// Layers 同 All, 但把每个 error 解析为 Layer, 调用方无需再区分 *Code、wrapper 等内部类型
+func Layers(err error) iter.Seq[Layer] {
+	return func(yield func(Layer) bool) {
+		walk(err, 0, func(err error, depth int) bool {
+			return yield(toLayer(err, depth))
+		})
+	}
+}
+
+func walk(err error, depth int, yield func(error, int) bool) bool {
+	for err != nil {
+		if !yield(err, depth) {
+			return false
+		}
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case multiError:
+			for _, sub := range e.Unwrap() {
+				if !walk(sub, depth+1, yield) {
+					return false
+				}
+			}
+			return true
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+func toLayer(err error, depth int) (l Layer) {
+	l = Layer{Kind: LayerForeign, Err: err, Depth: depth, Code: DefaultCode}
+	switch e := err.(type) {
+	case *Code:
+		l.Kind, l.Msg, l.Code, l.Attrs = LayerCode, e.msg, e.code, e.fields
+		if stack := e.Stack(); len(stack) > 0 {
+			l.Caller = stack[0]
+		}
+	case *wrapper:
+		l.Kind, l.Msg, l.Attrs = LayerWrapper, e.msg, e.fields
+		l.Caller = e.parse().stack
+		if e.code != nil {
+			l.Code = e.code.code
+		}
+	case *joinError:
+		l.Kind = LayerJoin
+		if stack := e.Stack(); len(stack) > 0 {
+			l.Caller = stack[0]
+		}
+	default:
+		l.Msg = err.Error()
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package errors
+
+import (
+	stderrs "errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayers(t *testing.T) {
+	c := NewCode(0, errCode, errMsg).With("uid", 7)
+	err := WrapCode(Wrap(c, errTrace), NewCode(-1, 2002, ""), "outer")
+
+	var ls []Layer
+	for l := range Layers(err) {
+		ls = append(ls, l)
+	}
+	if !assert.Len(t, ls, 3) {
+		return
+	}
+	assert.Equal(t, LayerWrapper, ls[0].Kind)
+	assert.Equal(t, "outer", ls[0].Msg)
+	assert.Equal(t, 2002, ls[0].Code)
+	assert.Contains(t, ls[0].Caller, "iter_test.go")
+
+	assert.Equal(t, LayerWrapper, ls[1].Kind)
+	assert.Equal(t, errTrace, ls[1].Msg)
+	assert.Equal(t, DefaultCode, ls[1].Code)
+
+	assert.Equal(t, LayerCode, ls[2].Kind)
+	assert.Equal(t, errMsg, ls[2].Msg)
+	assert.Equal(t, errCode, ls[2].Code)
+	assert.Equal(t, []Field{{Key: "uid", Value: 7}}, ls[2].Attrs)
+	assert.True(t, strings.Contains(ls[2].Caller, "TestLayers"), ls[2].Caller)
+	assert.Equal(t, c, ls[2].Err)
+
+	t.Run("multi", func(t *testing.T) {
+		err := Wrap(Join(Wrap(io.EOF, "a"), stderrs.Join(io.ErrClosedPipe, c)), "top")
+		var kinds []string
+		var depths []int
+		for l := range Layers(err) {
+			kinds = append(kinds, l.Kind.String())
+			depths = append(depths, l.Depth)
+		}
+		assert.Equal(t, []string{"wrapper", "join", "wrapper", "foreign", "foreign", "foreign", "code"}, kinds)
+		assert.Equal(t, []int{0, 0, 1, 1, 1, 2, 2}, depths)
+
+		n := 0
+		for e := range All(err) {
+			n++
+			if e == io.EOF {
+				break
+			}
+		}
+		assert.Equal(t, 4, n)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		for range All(nil) {
+			t.Fatal("unexpected layer")
+		}
+	})
+}
@@ -0,0 +1,217 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// multiError 标准库 errors.Join 和 fmt.Errorf 多个 %w 时生成的 error 都实现了此接口
+type multiError interface {
+	Unwrap() []error
+}
+
+type joinError struct {
+	errs  []error
+	cache *callers
+	skip  int
+}
+
+// Join 替换 errors.Join, 会额外记录调用 Join 处的调用栈; errs 中的 nil 会被忽略, 全为 nil 时返回 nil
+func Join(errs ...error) error {
+	n := 0
+	for _, err := range errs {
+		if err != nil {
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	e := &joinError{
+		errs: make([]error, 0, n),
+	}
+	for _, err := range errs {
+		if err != nil {
+			e.errs = append(e.errs, err)
+		}
+	}
+	c := newStackCode(1)
+	e.cache, e.skip = c.cache, c.skip
+	return e
+}
+
+func (e *joinError) Unwrap() []error {
+	return e.errs
+}
+
+func (e *joinError) Stack() (stack []string) {
+	if e.cache == nil {
+		return
+	}
+	if len(e.cache.stack) > e.skip {
+		return e.cache.stack[e.skip:]
+	}
+	return
+}
+
+// Error 输出包含所有分支的缩进树; ErrorMsg、ErrorCaller 模式下输出单行的 "[a; b][ caller]"
+func (e *joinError) Error() string {
+	if mode := GetErrorMode(); mode == ErrorMsg || mode == ErrorCaller {
+		return string(e.appendError(nil, mode))
+	}
+	buf := &writeBuffer{}
+	writeJoinText(buf, e.errs, e.cache, e.skip)
+	return buf.String()
+}
+
+// appendError 同 wrapper.appendError, 各个分支按各自的 Error() 输出
+func (e *joinError) appendError(bs []byte, mode ErrorMode) []byte {
+	bs = append(bs, '[')
+	for i, err := range e.errs {
+		if i > 0 {
+			bs = append(bs, "; "...)
+		}
+		bs = append(bs, causeText(err)...)
+	}
+	bs = append(bs, ']')
+	if stack := e.Stack(); mode == ErrorCaller && len(stack) > 0 {
+		bs = append(bs, ' ')
+		bs = append(bs, stack[0]...)
+	}
+	return bs
+}
+
+func (e *joinError) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(e), nil
+}
+
+func (e *joinError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('#') {
+			io.WriteString(s, e.GoString())
+			return
+		}
+		if s.Flag('+') {
+			WriteText(s, e)
+			return
+		}
+		s.Write([]byte(e.Error()))
+	case 's':
+		s.Write([]byte(e.Error()))
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// GoString fmt.GoStringer 的方法, 用于 %#v
+func (e *joinError) GoString() string {
+	buf := &strings.Builder{}
+	buf.WriteString("&errors.joinError{errs:[]error{")
+	for i, err := range e.errs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(buf, "%#v", err)
+	}
+	buf.WriteByte('}')
+	if stack := e.Stack(); len(stack) > 0 {
+		fmt.Fprintf(buf, ", stack:%#v", stack)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+func multiStack(e multiError) (cs *callers, skip int) {
+	if j, ok := e.(*joinError); ok {
+		return j.cache, j.skip
+	}
+	return
+}
+
+// appendJoinJSON 按 schema s 追加 `{"errors":[...],"stack":[...]}`, 每个分支都是一个完整的 MarshalJSON(MarshalJSON2) 结果
+func appendJoinJSON(bs []byte, errs []error, cs *callers, skip int, json2 bool, s *jsonSchema) []byte {
+	bs = append(bs, '{')
+	bs = append(bs, s.errors...)
+	bs = append(bs, '[')
+	for i, err := range errs {
+		if i != 0 {
+			bs = append(bs, ',')
+		}
+		if json2 {
+			bs = appendJSON2(bs, err, s)
+		} else {
+			buf := writeBuffer{buf: bs}
+			writeJSON(&buf, err, s)
+			bs = buf.buf
+		}
+	}
+	bs = append(bs, ']')
+	if cs != nil && len(cs.stack) > skip {
+		bs = s.appendStack(bs, cs, skip, len(cs.stack))
+	}
+	return append(bs, '}')
+}
+
+// writeJoinText 输出:
+//
+//	2 errors;
+//	    (file:line) func;
+//	  [0] branch0
+//	  [1] branch1
+//
+// 分支内的换行会再缩进一层, 因此嵌套的 Join 会形成一棵树
+func writeJoinText(buf *writeBuffer, errs []error, cs *callers, skip int) {
+	buf.WriteString(strconv.Itoa(len(errs)))
+	buf.WriteString(" errors;")
+	if cs != nil && len(cs.stack) > skip {
+		for i, str := range cs.stack[skip:] {
+			if i != 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString("\n    ")
+			buf.WriteString(str)
+		}
+		buf.WriteByte(';')
+	}
+	for i, err := range errs {
+		buf.WriteString("\n  [")
+		buf.WriteString(strconv.Itoa(i))
+		buf.WriteString("] ")
+		sub := &writeBuffer{}
+		marshalText(0, sub, err)
+		start := 0
+		for j, c := range sub.buf {
+			if c == '\n' {
+				buf.Write(sub.buf[start : j+1])
+				buf.WriteString("    ")
+				start = j + 1
+			}
+		}
+		buf.Write(sub.buf[start:])
+	}
+}
@@ -0,0 +1,65 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrs "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoin(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		assert.Nil(t, Join())
+		assert.Nil(t, Join(nil, nil))
+	})
+
+	t.Run("Unwrap", func(t *testing.T) {
+		err1, err2 := stderrs.New(errMsg), NewCode(0, errCode, errMsg)
+		err := Join(err1, nil, err2)
+		assert.Equal(t, []error{err1, err2}, err.(multiError).Unwrap())
+		assert.True(t, len(err.(*joinError).Stack()) > 0)
+		assert.True(t, stderrs.Is(err, err1))
+		assert.True(t, Is(err, err1))
+		assert.True(t, Is(Wrap(err, errTrace), NewCode(0, errCode, "")))
+		assert.False(t, Is(err, NewCode(0, errCode+1, "")))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		c := &Code{code: errCode, msg: errMsg, cache: &callers{stack: []string{"(file1:88) func1"}}}
+		w := Wrap(stderrs.New(errMsg), errTrace)
+		w.(*wrapper).pc = testFrame
+		err := &joinError{errs: []error{c, w}}
+		var e error = Wrap(err, errTrace)
+		e.(*wrapper).pc = testFrame
+
+		str := `{"cause":{"errors":[` +
+			`{"cause":{"code":88888,"msg":"msg!","stack":["(file1:88) func1"]},"wrapper":[]},` +
+			`{"cause":"msg!","wrapper":[{"trace":"trace!","caller":"(file1:88) func1"}]}` +
+			`]},"wrapper":[{"trace":"trace!","caller":"(file1:88) func1"}]}`
+		assert.Equal(t, str, string(MarshalJSON(e)))
+		assert.Equal(t, str, string(MarshalJSON2(e)))
+		bs, _ := json.Marshal(err)
+		assert.True(t, json.Valid(bs))
+
+		// 标准库的 Join 也按分支展开
+		bs = MarshalJSON(stderrs.Join(stderrs.New("a"), stderrs.New("b")))
+		assert.Equal(t, `{"cause":{"errors":[{"cause":"a","wrapper":[]},{"cause":"b","wrapper":[]}]},"wrapper":[]}`, string(bs))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		c := &Code{code: errCode, msg: errMsg, cache: &callers{stack: []string{"(file1:88) func1"}}}
+		inner := &joinError{errs: []error{stderrs.New("a"), stderrs.New("b")}}
+		err := &joinError{
+			errs:  []error{c, inner},
+			cache: &callers{stack: []string{"(file2:99) func2"}},
+		}
+		str := "2 errors;\n    (file2:99) func2;" +
+			"\n  [0] 88888, msg!;\n        (file1:88) func1;" +
+			"\n  [1] 2 errors;\n      [0] a;\n      [1] b;"
+		assert.Equal(t, str, err.Error())
+		assert.Equal(t, str, string(MarshalText(err)))
+		assert.Equal(t, str, fmt.Sprintf("%+v", err))
+	})
+}
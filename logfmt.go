@@ -0,0 +1,242 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// TextMode MarshalText、AppendText、WriteText 及 %+v 的输出格式
+type TextMode int32
+
+const (
+	TextMultiLine TextMode = iota // 默认格式, 每层、每个 frame 各占一行
+	TextLogfmt                    // 单行 logfmt 格式, 同 MarshalLogfmt
+)
+
+var textMode int32 // TextMode
+
+// SetTextMode 设置全局的文本输出格式
+func SetTextMode(mode TextMode) {
+	atomic.StoreInt32(&textMode, int32(mode))
+}
+
+// GetTextMode 返回全局的文本输出格式
+func GetTextMode() TextMode {
+	return TextMode(atomic.LoadInt32(&textMode))
+}
+
+// MarshalLogfmt 把 err 序列化为单行的 logfmt 格式, 如:
+//
+//	code=1001 msg="not found" stack="(a.go:1) a|(b.go:2) b" wrap="load@c.go:3|handle@d.go:4" uid=7
+//
+// code 以 CodeOf 为准; msg、stack 取自最外层的 *Code, 没有 *Code 时 stack 取自 Join 的调用栈;
+// cause 为非本包 error 的 Error(); join 为 Join、errors.Join 的各个分支, 每个分支按 ErrorMsg 模式输出;
+// wrap 由内向外排列; stack、join、wrap 的各项以 '|' 分隔, 项内的 '|' 转义为 `\|`; 最后是各层的 fields。
+// SetTextMode(TextLogfmt) 后 %+v、MarshalText 也输出此格式
+func MarshalLogfmt(err error) []byte {
+	return appendLogfmt(nil, err)
+}
+
+// AppendLogfmt 将 MarshalLogfmt 的结果追加到 dst 后返回
+func AppendLogfmt(dst []byte, err error) []byte {
+	return appendLogfmt(dst, err)
+}
+
+// WriteLogfmt 将 MarshalLogfmt 的结果写入 w, 序列化使用的 buffer 来自 pool
+func WriteLogfmt(w io.Writer, err error) (n int, e error) {
+	buf := getBuffer()
+	buf.buf = appendLogfmt(buf.buf, err)
+	n, e = w.Write(buf.buf)
+	putBuffer(buf)
+	return
+}
+
+func appendLogfmt(bs []byte, err error) []byte {
+	if err == nil {
+		return bs
+	}
+	var (
+		code  *Code
+		cause error
+		join  multiError
+		arr   [8]*wrapper
+		ws    = arr[:0] // 外层在前
+	)
+	for e := err; e != nil; {
+		switch x := e.(type) {
+		case *Code:
+			if code == nil {
+				code = x
+			}
+			e = x.err
+		case *wrapper:
+			ws = append(ws, x)
+			e = x.err
+		case multiError:
+			// 与 MarshalText 一样展开 Join 的各个分支, 但不再继续展开分支内部
+			join, e = x, nil
+		default:
+			cause, e = x, nil
+		}
+	}
+
+	bs = append(bs, "code="...)
+	bs = strconv.AppendInt(bs, int64(CodeOf(err)), 10)
+	var stack []string
+	if code != nil {
+		bs = append(bs, " msg="...)
+		bs = appendTextValue(bs, code.msg)
+		stack = code.Stack()
+	} else if j, ok := join.(*joinError); ok {
+		stack = j.Stack()
+	}
+	if len(stack) > 0 {
+		bs = append(bs, ` stack="`...)
+		for i, str := range stack {
+			if i != 0 {
+				bs = append(bs, '|')
+			}
+			bs = appendQuoted(bs, str)
+		}
+		bs = append(bs, '"')
+	}
+	if cause != nil {
+		bs = append(bs, " cause="...)
+		bs = appendTextValue(bs, causeText(cause))
+	}
+	if join != nil {
+		bs = append(bs, ` join="`...)
+		for i, err := range join.Unwrap() {
+			if i != 0 {
+				bs = append(bs, '|')
+			}
+			bs = appendQuoted(bs, string(appendErrorMsg(nil, err)))
+		}
+		bs = append(bs, '"')
+	}
+	if len(ws) > 0 {
+		bs = append(bs, ` wrap="`...)
+		for i := len(ws) - 1; i >= 0; i-- {
+			if i != len(ws)-1 {
+				bs = append(bs, '|')
+			}
+			bs = appendQuoted(bs, ws[i].msg)
+			bs = append(bs, '@')
+			bs = appendQuoted(bs, fileLine(ws[i].parse().stack))
+		}
+		bs = append(bs, '"')
+	}
+	// 与 Fields 一样外层在前
+	for e := err; e != nil; {
+		switch x := e.(type) {
+		case *Code:
+			bs = appendFieldsText(bs, x.fields)
+			e = x.err
+		case *wrapper:
+			bs = appendFieldsText(bs, x.fields)
+			e = x.err
+		default:
+			e = nil
+		}
+	}
+	return bs
+}
+
+// appendQuoted 同 strconv.AppendQuote, 但不加首尾的引号, 用于拼接多个值到一个带引号的字符串中;
+// '|' 转义为 `\|`, 以免与分隔符混淆
+func appendQuoted(bs []byte, s string) []byte {
+	n := len(bs)
+	if !needQuote(s) {
+		bs = append(bs, s...)
+	} else {
+		bs = strconv.AppendQuote(bs, s)
+		copy(bs[n:], bs[n+1:len(bs)-1])
+		bs = bs[:len(bs)-2]
+	}
+	if strings.IndexByte(s, '|') < 0 {
+		return bs
+	}
+	quoted := string(bs[n:])
+	bs = bs[:n]
+	for i := 0; i < len(quoted); i++ {
+		if quoted[i] == '|' {
+			bs = append(bs, '\\')
+		}
+		bs = append(bs, quoted[i])
+	}
+	return bs
+}
+
+// appendErrorMsg 同 ErrorMsg 模式的 Error(), 但整条错误链都按 ErrorMsg 输出, 不受全局 ErrorMode 影响;
+// 非本包的 error 使用 Error()
+func appendErrorMsg(bs []byte, err error) []byte {
+	n := len(bs)
+	switch e := err.(type) {
+	case *Code:
+		bs = strconv.AppendInt(bs, int64(e.code), 10)
+		bs = append(bs, ", "...)
+		bs = append(bs, e.msg...)
+		err = e.err
+	case *wrapper:
+		if e.code != nil {
+			bs = strconv.AppendInt(bs, int64(e.code.code), 10)
+			bs = append(bs, ", "...)
+		}
+		bs = append(bs, e.msg...)
+		err = e.err
+	case *joinError:
+		bs = append(bs, '[')
+		for i, err := range e.errs {
+			if i > 0 {
+				bs = append(bs, "; "...)
+			}
+			bs = appendErrorMsg(bs, err)
+		}
+		return append(bs, ']')
+	default:
+		return append(bs, causeText(err)...)
+	}
+	if err != nil {
+		if len(bs) > n {
+			bs = append(bs, ": "...)
+		}
+		bs = appendErrorMsg(bs, err)
+	}
+	return bs
+}
+
+// fileLine 从 "(file:line) func" 格式的 caller 中取出 file:line
+func fileLine(caller string) string {
+	if len(caller) > 0 && caller[0] == '(' {
+		for i := 1; i < len(caller); i++ {
+			if caller[i] == ')' {
+				return caller[1:i]
+			}
+		}
+	}
+	return caller
+}
@@ -0,0 +1,55 @@
+package errors
+
+import (
+	stderrs "errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalLogfmt(t *testing.T) {
+	c := NewCode(0, errCode, "not found").With("uid", 7)
+	err := WrapCode(Wrap(c, "load \"user\""), NewCode(-1, 2002, ""), "handle").(*wrapper).With("req", "a b")
+
+	s := string(MarshalLogfmt(err))
+	assert.NotContains(t, s, "\n")
+	assert.True(t, strings.HasPrefix(s, `code=2002 msg="not found" stack="(`), s)
+	assert.Contains(t, s, `TestMarshalLogfmt"`)
+	assert.Contains(t, s, ` wrap="load \"user\"@`)
+	assert.Contains(t, s, `|handle@`)
+	assert.True(t, strings.HasSuffix(s, ` req="a b" uid=7`), s)
+	t.Log(s)
+
+	s = string(MarshalLogfmt(Wrap(io.EOF, "read")))
+	assert.True(t, strings.HasPrefix(s, `code=-1 cause=EOF wrap="read@`), s)
+	assert.Equal(t, "", string(MarshalLogfmt(nil)))
+	assert.Equal(t, `code=1 msg="a\nb"`, string(MarshalLogfmt(&Code{code: 1, msg: "a\nb"})))
+
+	t.Run("join", func(t *testing.T) {
+		err := Join(&Code{code: 1, msg: "a|b"}, Wrap(&Code{code: 2, msg: "c"}, "d"), io.EOF)
+		s := string(MarshalLogfmt(err))
+		assert.NotContains(t, s, "\n")
+		assert.True(t, strings.HasPrefix(s, `code=1 stack="(`), s)
+		assert.True(t, strings.HasSuffix(s, ` join="1, a\|b|d: 2, c|EOF"`), s)
+
+		s = string(MarshalLogfmt(stderrs.Join(io.EOF, io.ErrUnexpectedEOF)))
+		assert.Equal(t, `code=-1 join="EOF|unexpected EOF"`, s)
+	})
+
+	t.Run("escape", func(t *testing.T) {
+		c := &Code{code: 1, msg: "m", cache: &callers{stack: []string{"(a.go:1) a|b", "(b.go:2) b"}}}
+		assert.Equal(t, `code=1 msg=m stack="(a.go:1) a\|b|(b.go:2) b"`, string(MarshalLogfmt(c)))
+	})
+
+	t.Run("TextMode", func(t *testing.T) {
+		defer SetTextMode(TextMultiLine)
+		assert.Contains(t, string(MarshalText(err)), "\n")
+		SetTextMode(TextLogfmt)
+		assert.Equal(t, GetTextMode(), TextLogfmt)
+		assert.Equal(t, string(MarshalLogfmt(err)), string(MarshalText(err)))
+		assert.Equal(t, string(MarshalLogfmt(err)), fmt.Sprintf("%+v", err))
+	})
+}
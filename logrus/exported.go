@@ -79,8 +79,34 @@ func toLogger(logger *logrus.Logger) *Logger {
 func New() *Logger {
 	return toLogger(logrus.New())
 }
+
+// SetPathMode 设置 logger 及其生成的 Entry 输出 caller 时的文件路径格式,
+// 默认 errors.PathDefault 表示跟随 errors.SetPathMode;
+// 设置保存在 logger 的 Hooks 中, 首次调用会增加一个 hook, 因此应在开始输出日志前调用, ReplaceHooks 会清除该设置
+func (logger *Logger) SetPathMode(mode errors.PathMode) {
+	l := toLogrusLogger(logger)
+	h := findPathModeHook(l)
+	if h == nil {
+		h = &pathModeHook{}
+		h.mode.Store(int32(mode))
+		l.AddHook(h)
+		return
+	}
+	h.mode.Store(int32(mode))
+}
+
+// PathMode 返回 logger 输出 caller 时的文件路径格式
+func (logger *Logger) PathMode() errors.PathMode {
+	return pathModeOf(toLogrusLogger(logger))
+}
+
+// SetPathMode 设置 standard logger 的文件路径格式, 见 Logger.SetPathMode
+func SetPathMode(mode errors.PathMode) {
+	toLogger(logrus.StandardLogger()).SetPathMode(mode)
+}
+
 func (logger *Logger) AddCaller(pc errors.PC) *Entry {
-	c := pc.CallerFrame()
+	c := pc.CallerFrameMode(logger.PathMode())
 	return logger.WithFields(logrus.Fields{
 		logrus.FieldKeyFunc: c.Func,
 		logrus.FieldKeyFile: c.FileLine,
@@ -24,6 +24,7 @@ package logrus
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -31,6 +32,35 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// pathModeHook 不输出任何内容, 只用于在 logger 上保存输出 caller 时的文件路径格式, 见 Logger.SetPathMode;
+// Logger、Entry 都是 logrus 类型的别名视图, 无法增加字段, 因此借用 logger 的 Hooks 保存
+type pathModeHook struct {
+	mode atomic.Int32 // errors.PathMode
+}
+
+func (h *pathModeHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *pathModeHook) Fire(*logrus.Entry) error { return nil }
+
+func findPathModeHook(logger *logrus.Logger) *pathModeHook {
+	if logger == nil {
+		return nil
+	}
+	for _, h := range logger.Hooks[logrus.PanicLevel] {
+		if h, ok := h.(*pathModeHook); ok {
+			return h
+		}
+	}
+	return nil
+}
+
+func pathModeOf(logger *logrus.Logger) errors.PathMode {
+	if h := findPathModeHook(logger); h != nil {
+		return errors.PathMode(h.mode.Load())
+	}
+	return errors.PathDefault
+}
+
 type Fields = logrus.Fields
 type Level = logrus.Level
 
@@ -51,7 +81,7 @@ func NewEntry(logger *logrus.Logger) *Entry {
 }
 
 func (entry *Entry) AddCaller(pc errors.PC) *logrus.Entry {
-	c := pc.CallerFrame()
+	c := pc.CallerFrameMode(pathModeOf(entry.Logger))
 	return toLogrusEntry(entry).WithFields(logrus.Fields{
 		logrus.FieldKeyFunc: c.Func,
 		logrus.FieldKeyFile: c.FileLine,
@@ -6,6 +6,7 @@ import (
 	"io"
 	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/lxt1045/errors"
@@ -157,3 +158,58 @@ func BenchmarkLogrusCaller(b *testing.B) {
 		}).Info("some log messages")
 	}
 }
+
+func TestPathMode(t *testing.T) {
+	defer SetPathMode(errors.PathDefault)
+	_, file, _, _ := runtime.Caller(0)
+	dir := file[:strings.LastIndexByte(file, '/')+1]
+
+	w := &bytes.Buffer{}
+	logrus.SetReportCaller(false)
+	logrus.SetOutput(w)
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+
+	SetPathMode(errors.PathFull)
+	WithContext(context.TODO()).Info("info msg")
+	if !strings.Contains(w.String(), `"file":"`+dir) {
+		t.Fatal(w.String())
+	}
+
+	w.Reset()
+	SetPathMode(errors.PathDefault)
+	WithContext(context.TODO()).Info("info msg")
+	if !strings.Contains(w.String(), `"file":"logrus/`) {
+		t.Fatal(w.String())
+	}
+}
+
+func TestLoggerPathMode(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	dir := file[:strings.LastIndexByte(file, '/')+1]
+
+	w1, w2 := &bytes.Buffer{}, &bytes.Buffer{}
+	l1, l2 := New(), New()
+	l1.SetOutput(w1)
+	l2.SetOutput(w2)
+	l1.SetFormatter(&logrus.JSONFormatter{})
+	l2.SetFormatter(&logrus.JSONFormatter{})
+	l1.SetPathMode(errors.PathFull)
+
+	l1.AddCaller(errors.GetPC()).Info("info msg")
+	l2.AddCaller(errors.GetPC()).Info("info msg")
+	if !strings.Contains(w1.String(), `"file":"`+dir) {
+		t.Fatal(w1.String())
+	}
+	if !strings.Contains(w2.String(), `"file":"logrus/`) {
+		t.Fatal(w2.String())
+	}
+	if l1.PathMode() != errors.PathFull || l2.PathMode() != errors.PathDefault {
+		t.Fatal(l1.PathMode(), l2.PathMode())
+	}
+
+	// 再次设置只修改已有的 hook
+	l1.SetPathMode(errors.PathModule)
+	if n := len(l1.Hooks[logrus.InfoLevel]); n != 1 || l1.PathMode() != errors.PathModule {
+		t.Fatal(n, l1.PathMode())
+	}
+}
@@ -0,0 +1,206 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"net/url"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// PathMode 决定 caller 中文件路径的格式; 正数 n 表示保留最后 n 段路径, 见 PathLast
+type PathMode int32
+
+const (
+	// PathDefault 用于日志适配器等, 表示使用全局的 GetPathMode(); SetPathMode(PathDefault) 恢复为 PathLast(2)
+	PathDefault PathMode = 0
+	// PathFull 完整路径, 如 /home/user/go/src/github.com/a/b/internal/handler.go
+	PathFull PathMode = -1
+	// PathModule 相对于所在 Go module 根目录的路径, 如 internal/handler.go
+	PathModule PathMode = -2
+	// PathImport import path + 文件名, 如 github.com/a/b/internal/handler.go
+	PathImport PathMode = -3
+)
+
+var pathMode int32 = 2 // 全局的路径格式, 默认 PathLast(2)
+
+// PathLast 保留最后 n 段路径, n <= 0 时为 2; PathLast(2) 是默认格式, 如 internal/handler.go
+func PathLast(n int) PathMode {
+	if n <= 0 {
+		n = 2
+	}
+	return PathMode(n)
+}
+
+// SetPathMode 设置全局的路径格式; 缓存以路径格式区分, 设置后新生成的调用栈立即使用新格式,
+// 已经生成的 error 保持原有的调用栈
+func SetPathMode(mode PathMode) {
+	if mode == PathDefault {
+		mode = PathLast(2)
+	}
+	atomic.StoreInt32(&pathMode, int32(mode))
+}
+
+// GetPathMode 返回全局的路径格式
+func GetPathMode() PathMode {
+	return PathMode(atomic.LoadInt32(&pathMode))
+}
+
+// Resolve PathDefault 时返回全局的路径格式, 否则返回 mode 本身
+func (mode PathMode) Resolve() PathMode {
+	if mode == PathDefault {
+		return GetPathMode()
+	}
+	return mode
+}
+
+func (mode PathMode) String() string {
+	switch mode {
+	case PathDefault:
+		return "default"
+	case PathFull:
+		return "full"
+	case PathModule:
+		return "module"
+	case PathImport:
+		return "import"
+	}
+	if mode > 0 {
+		return "last" + strconv.Itoa(int(mode))
+	}
+	return "PathMode(" + strconv.Itoa(int(mode)) + ")"
+}
+
+// trimPath 按 mode 处理 file; funcName 为 runtime.Frame.Function, 用于计算 import path
+func trimPath(file, funcName string, mode PathMode) string {
+	switch mode {
+	case PathFull:
+		return file
+	case PathModule:
+		pkg := pkgPath(funcName)
+		if pkg == "" {
+			break
+		}
+		if mod := modulePathOf(pkg); mod != "" {
+			pkg = strings.TrimPrefix(pkg[len(mod):], "/")
+		}
+		if pkg == "" {
+			return fileBase(file)
+		}
+		return pkg + "/" + fileBase(file)
+	case PathImport:
+		if pkg := pkgPath(funcName); pkg != "" {
+			return pkg + "/" + fileBase(file)
+		}
+	default:
+		if mode > 0 {
+			return lastSegments(file, int(mode))
+		}
+	}
+	return lastSegments(file, 2)
+}
+
+// lastSegments 保留 file 的最后 n 段
+func lastSegments(file string, n int) string {
+	i := len(file)
+	for ; n > 0; n-- {
+		i = lastSeparator(file[:i])
+		if i < 0 {
+			return file
+		}
+	}
+	return file[i+1:]
+}
+
+func lastSeparator(file string) int {
+	i := strings.LastIndex(file, pathSeparator)
+	if !samePathSeparator {
+		if j := strings.LastIndexByte(file, '/'); j > i {
+			i = j
+		}
+	}
+	return i
+}
+
+func fileBase(file string) string {
+	return file[lastSeparator(file)+1:]
+}
+
+// pkgPath 从 runtime.Frame.Function 中取出 import path, 如 github.com/a/b.(*T).F ==> github.com/a/b;
+// main 包的函数名不包含 import path, 返回 ""。
+// 函数名中 import path 最后一段的 '.' 会被转义为 %2e, 如 gopkg.in/yaml%2ev3.Unmarshal,
+// 因此最后一个 '/' 之后的第一个 '.' 就是 import path 与函数名的分界, 取出后再还原转义
+func pkgPath(funcName string) string {
+	if i := strings.IndexByte(funcName, '['); i >= 0 {
+		funcName = funcName[:i] // 泛型函数的类型参数中也可能有 '/' 和 '.'
+	}
+	i := strings.LastIndexByte(funcName, '/')
+	j := strings.IndexByte(funcName[i+1:], '.')
+	if j < 0 {
+		return ""
+	}
+	pkg := funcName[:i+1+j]
+	if pkg == "main" {
+		return ""
+	}
+	if strings.IndexByte(pkg, '%') >= 0 {
+		if p, err := url.PathUnescape(pkg); err == nil {
+			pkg = p
+		}
+	}
+	return pkg
+}
+
+var (
+	modulePathsOnce sync.Once
+	modulePaths     []string // 按长度降序, 以便最长匹配
+)
+
+// modulePathOf 返回 pkg 所属 module 的 path; 标准库等找不到 module 时返回 ""
+func modulePathOf(pkg string) string {
+	modulePathsOnce.Do(func() {
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		if bi.Main.Path != "" {
+			modulePaths = append(modulePaths, bi.Main.Path)
+		}
+		for _, dep := range bi.Deps {
+			modulePaths = append(modulePaths, dep.Path)
+		}
+		sort.Slice(modulePaths, func(i, j int) bool {
+			return len(modulePaths[i]) > len(modulePaths[j])
+		})
+	})
+	for _, mod := range modulePaths {
+		if pkg == mod || (strings.HasPrefix(pkg, mod) && pkg[len(mod)] == '/') {
+			return mod
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package errors
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimPath(t *testing.T) {
+	file := "/home/go/src/github.com/lxt1045/errors/httperr/problem.go"
+	fn := "github.com/lxt1045/errors/httperr.(*Problem).Write"
+	cases := []struct {
+		mode PathMode
+		file string
+		fn   string
+		want string
+	}{
+		{PathLast(2), file, fn, "httperr/problem.go"},
+		{PathLast(1), file, fn, "problem.go"},
+		{PathLast(3), file, fn, "errors/httperr/problem.go"},
+		{PathLast(100), file, fn, file},
+		{PathFull, file, fn, file},
+		{PathImport, file, fn, "github.com/lxt1045/errors/httperr/problem.go"},
+		{PathModule, file, fn, "httperr/problem.go"},
+		{PathModule, "/home/go/src/github.com/lxt1045/errors/code.go", "github.com/lxt1045/errors.NewCode", "code.go"},
+		{PathModule, "/usr/local/go/src/fmt/print.go", "fmt.Println", "fmt/print.go"},
+		{PathModule, "/app/cmd/main.go", "main.main", "cmd/main.go"},
+		{PathImport, "/app/cmd/main.go", "main.main", "cmd/main.go"},
+		{PathImport, "/mod/gopkg.in/yaml.v3@v3.0.1/decode.go", "gopkg.in/yaml%2ev3.(*decoder).unmarshal", "gopkg.in/yaml.v3/decode.go"},
+		{PathModule, "/mod/gopkg.in/yaml.v3@v3.0.1/decode.go", "gopkg.in/yaml%2ev3.(*decoder).unmarshal", "decode.go"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, trimPath(c.file, c.fn, c.mode), c.mode.String())
+	}
+
+	t.Run("pkgPath", func(t *testing.T) {
+		for fn, want := range map[string]string{
+			"github.com/a/b.(*T).F":                         "github.com/a/b",
+			"github.com/a/b.F.func1":                        "github.com/a/b",
+			"gopkg.in/yaml%2ev3.Unmarshal":                  "gopkg.in/yaml.v3",
+			"gopkg.in/yaml%2ev3.(*decoder).unmarshal":       "gopkg.in/yaml.v3",
+			"github.com/a/b.F[go.shape.*github.com/c/d.T]":  "github.com/a/b",
+			"github.com/a/b.(*T[go.shape.*gopkg.in/x.y]).M": "github.com/a/b",
+			"fmt.Println":                                   "fmt",
+			"main.main":                                     "",
+		} {
+			assert.Equal(t, want, pkgPath(fn), fn)
+		}
+	})
+}
+
+func TestSetPathMode(t *testing.T) {
+	defer SetPathMode(PathDefault)
+	newCode := func() *Code {
+		return NewCode(0, errCode, errMsg)
+	}
+	_, file, _, _ := runtime.Caller(0)
+
+	t.Run("NewCode", func(t *testing.T) {
+		SetPathMode(PathFull)
+		assert.True(t, strings.HasPrefix(newCode().Stack()[0], "("+file+":"))
+
+		SetPathMode(PathLast(1))
+		assert.True(t, strings.HasPrefix(newCode().Stack()[0], "(path_test.go:"))
+
+		SetPathMode(PathImport)
+		assert.True(t, strings.HasPrefix(newCode().Stack()[0], "(github.com/lxt1045/errors/path_test.go:"))
+
+		SetPathMode(PathDefault)
+		assert.Equal(t, PathLast(2), GetPathMode())
+		assert.True(t, strings.HasPrefix(newCode().Stack()[0], "("+lastSegments(file, 2)+":"))
+	})
+
+	t.Run("Wrap", func(t *testing.T) {
+		err := Wrap(newCode(), errTrace).(*wrapper)
+		SetPathMode(PathFull)
+		assert.True(t, strings.HasPrefix(err.parse().stack, "("+file+":"))
+		SetPathMode(PathModule)
+		assert.True(t, strings.HasPrefix(err.parse().stack, "(path_test.go:"))
+	})
+
+	t.Run("CallerFrameMode", func(t *testing.T) {
+		SetPathMode(PathDefault)
+		pc := callerPC()
+		assert.Equal(t, file, CallerFrameMode(uintptr(pc), PathFull).File)
+		assert.Equal(t, "path_test.go", pc.CallerFrameMode(PathModule).File)
+		assert.Equal(t, pc.CallerFrame().File, pc.CallerFrameMode(PathDefault).File)
+
+		var cs, cs1 []caller
+		func() {
+			cs, cs1 = CallersSkipMode(0, PathFull), CallersSkip(0)
+		}()
+		assert.Equal(t, file, cs[0].File)
+		assert.Equal(t, cs[0].Line, cs1[0].Line)
+		assert.True(t, strings.HasSuffix(file, "/"+cs1[0].File))
+	})
+}
+
+// callerPC GetPC 返回的是调用方的 pc, 需要禁止内联
+//
+//go:noinline
+func callerPC() PC {
+	return GetPC()
+}
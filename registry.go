@@ -0,0 +1,117 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"sort"
+	"sync"
+)
+
+var mCodeInfo sync.Map // code -> *CodeInfo
+
+// Severity 错误码的严重程度
+type Severity int8
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+	SeverityFatal
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarn:
+		return "warn"
+	case SeverityError:
+		return "error"
+	case SeverityFatal:
+		return "fatal"
+	}
+	return "unknown"
+}
+
+// CodeInfo 错误码的元数据, 每个错误码只能注册一次
+type CodeInfo struct {
+	Code       int
+	Name       string // 如: ORDER_NOT_FOUND
+	Msg        string // 默认错误信息
+	HTTPStatus int    // 对应的 HTTP 状态码, 0 表示未指定
+	Severity   Severity
+	Retryable  bool
+	Module     string // 声明此错误码的模块, 重复注册时用于提示冲突方
+}
+
+// RegisterCode 注册错误码, 返回一个不带调用栈的 *Code, 可用作 Is 比较的哨兵或 New/Clone 的模板;
+// 同一个错误码重复注册时返回 err
+func RegisterCode(info CodeInfo) (c *Code, err error) {
+	v, loaded := mCodeInfo.LoadOrStore(info.Code, &info)
+	if loaded {
+		old := v.(*CodeInfo)
+		err = NewCode(1, 0, "error code %d already registered by %q(%s), conflict with %q(%s)",
+			info.Code, old.Module, old.Name, info.Module, info.Name)
+		return
+	}
+	c = NewCode(-1, info.Code, info.Msg)
+	return
+}
+
+// DefineCode 同 RegisterCode, 但重复注册时 panic, 适合在包级变量初始化时使用:
+//
+//	var ErrOrderNotFound = errors.DefineCode(errors.CodeInfo{Code: 40401, Name: "ORDER_NOT_FOUND", ...})
+func DefineCode(info CodeInfo) *Code {
+	c, err := RegisterCode(info)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Lookup 查询已注册的错误码
+func Lookup(code int) (info CodeInfo, ok bool) {
+	v, ok := mCodeInfo.Load(code)
+	if !ok {
+		return
+	}
+	return *v.(*CodeInfo), true
+}
+
+// Codes 返回所有已注册的错误码, 按 Code 升序排列
+func Codes() (infos []CodeInfo) {
+	mCodeInfo.Range(func(_, v any) bool {
+		infos = append(infos, *v.(*CodeInfo))
+		return true
+	})
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Code < infos[j].Code
+	})
+	return
+}
+
+// Info 查询 e 的错误码注册信息
+func (e *Code) Info() (info CodeInfo, ok bool) {
+	return Lookup(e.code)
+}
@@ -0,0 +1,50 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	info := CodeInfo{
+		Code:       4040001,
+		Name:       "ORDER_NOT_FOUND",
+		Msg:        "order not found",
+		HTTPStatus: 404,
+		Severity:   SeverityWarn,
+		Module:     "order",
+	}
+	c := DefineCode(info)
+	assert.Equal(t, info.Code, c.Code())
+	assert.Equal(t, info.Msg, c.Msg())
+	assert.Nil(t, c.Stack())
+
+	got, ok := Lookup(info.Code)
+	assert.True(t, ok)
+	assert.Equal(t, info, got)
+	got, ok = c.New("order 1").Info()
+	assert.True(t, ok)
+	assert.Equal(t, info, got)
+	_, ok = Lookup(info.Code + 1)
+	assert.False(t, ok)
+
+	_, err := RegisterCode(CodeInfo{Code: info.Code, Name: "DUP", Module: "user"})
+	assert.NotNil(t, err)
+	assert.Panics(t, func() {
+		DefineCode(CodeInfo{Code: info.Code})
+	})
+	got, _ = Lookup(info.Code)
+	assert.Equal(t, info, got)
+
+	DefineCode(CodeInfo{Code: 4040000, Name: "NOT_FOUND"})
+	codes := Codes()
+	idx := -1
+	for i, ci := range codes {
+		if ci.Code == info.Code {
+			idx = i
+		}
+	}
+	assert.True(t, idx > 0)
+	assert.Equal(t, 4040000, codes[idx-1].Code)
+}
@@ -0,0 +1,102 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// RemoteHeader 跨服务传递错误时建议使用的 header 名
+const RemoteHeader = "X-Error-Remote"
+
+type remotePayload struct {
+	Service string   `json:"s,omitempty"`
+	Code    int      `json:"c"`
+	Msg     string   `json:"m,omitempty"`
+	Stack   []string `json:"k,omitempty"`
+}
+
+// EncodeRemote 把 AsCode(err) 编码为可以放在 header 或 body 字段中的紧凑字符串;
+// service 为当前(被调用方)服务名, 对端 DecodeRemote 后其调用栈会以 "[service] " 标记
+func EncodeRemote(service string, err error) string {
+	if err == nil {
+		return ""
+	}
+	c := AsCode(err)
+	p := remotePayload{Service: service, Code: c.code, Msg: c.msg, Stack: c.Stack()}
+	bs, _ := json.Marshal(&p)
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
+
+// DecodeRemote 是 EncodeRemote 的逆过程, 生成的 *Code 的调用栈先是对端的(已标记为 remote), 后接本地调用 DecodeRemote 处的
+func DecodeRemote(s string) (c *Code, err error) {
+	bs, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return
+	}
+	p := remotePayload{}
+	if err = json.Unmarshal(bs, &p); err != nil {
+		return
+	}
+	return NewRemoteCode(1, p.Service, p.Code, p.Msg, p.Stack), nil
+}
+
+// NewRemoteCode 用其它服务返回的 code、msg、stack 生成 *Code, 调用栈由两段组成:
+// 标记为 "[service] " 的远端 stack, 以及本地 skip 之上的调用栈; skip < 0 时不包含本地调用栈
+func NewRemoteCode(skip int, service string, code int, msg string, stack []string) (c *Code) {
+	if skip < 0 {
+		c = NewCode(-1, code, msg)
+	} else {
+		c = NewCode(skip+1, code, msg)
+	}
+	local := c.Stack()
+	all := make([]string, 0, len(stack)+len(local))
+	for _, str := range stack {
+		all = append(all, remoteFrame(service, str))
+	}
+	all = append(all, local...)
+	c.cache, c.skip = newCallers(all), 0
+	return
+}
+
+// remoteFrame 给 frame 加上服务名; 已经带有服务名的(多跳传递)保持不变
+func remoteFrame(service, frame string) string {
+	if service == "" || strings.HasPrefix(frame, "[") {
+		return frame
+	}
+	return "[" + service + "] " + frame
+}
+
+// RemoteService 若 frame 来自其它服务, 返回服务名
+func RemoteService(frame string) (service string, ok bool) {
+	if !strings.HasPrefix(frame, "[") {
+		return
+	}
+	i := strings.IndexByte(frame, ']')
+	if i < 0 {
+		return
+	}
+	return frame[1:i], true
+}
@@ -0,0 +1,43 @@
+package errors
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemote(t *testing.T) {
+	remote := &Code{code: errCode, msg: errMsg, cache: newCallers([]string{
+		"(service/peer_service.go:43) main.(*socksSvc).Auth",
+		"[svc-c] (rpc/method.go:25) rpc.SvcMethod.SvcInvoke",
+	})}
+	s := EncodeRemote("svc-b", Wrap(remote, errTrace))
+	assert.NotContains(t, s, "\n")
+
+	c, err := DecodeRemote(s)
+	assert.Nil(t, err)
+	assert.Equal(t, errCode, c.Code())
+	assert.Equal(t, errMsg, c.Msg())
+	assert.True(t, Is(c, remote))
+
+	stack := c.Stack()
+	assert.True(t, len(stack) > 2)
+	assert.Equal(t, "[svc-b] (service/peer_service.go:43) main.(*socksSvc).Auth", stack[0])
+	assert.Equal(t, "[svc-c] (rpc/method.go:25) rpc.SvcMethod.SvcInvoke", stack[1])
+	assert.True(t, strings.Contains(stack[2], "TestRemote"), stack[2])
+	service, ok := RemoteService(stack[1])
+	assert.True(t, ok)
+	assert.Equal(t, "svc-c", service)
+	_, ok = RemoteService(stack[2])
+	assert.False(t, ok)
+
+	c, err = DecodeRemote(EncodeRemote("svc-b", WrapCode(remote, NewCode(-1, 2002, ""), errTrace)))
+	assert.Nil(t, err)
+	assert.Equal(t, 2002, c.Code())
+	assert.Equal(t, errMsg, c.Msg())
+
+	_, err = DecodeRemote("!")
+	assert.NotNil(t, err)
+	assert.Equal(t, "", EncodeRemote("svc-b", nil))
+}
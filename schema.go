@@ -0,0 +1,165 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import "sync/atomic"
+
+// JSONSchema 定义 MarshalJSON、MarshalJSON2 等输出的字段名和布局; 字段名为空时使用默认值。
+// 注意: ParseJSON、Code.UnmarshalJSON 只能解析默认 schema 的输出
+type JSONSchema struct {
+	Cause   string // 默认 "cause", Flat 时不使用
+	Wrapper string // 默认 "wrapper", Flat 时不使用
+	Code    string // 默认 "code"
+	Msg     string // 默认 "msg"
+	Trace   string // 默认 "trace"
+	Caller  string // 默认 "caller"
+	Stack   string // 默认 "stack"
+	Fields  string // 默认 "fields"
+	Errors  string // 默认 "errors", Join 的各个分支
+
+	Flat        bool // 输出为 [cause, wrapper...] 数组, 非本包的 cause 输出为 {"msg":"..."}
+	StackString bool // stack 输出为以 "\n" 连接的单个字符串, 而不是字符串数组
+	OmitEmpty   bool // 省略值为空的 msg 和 trace
+}
+
+// jsonSchema 是预先拼接好的 JSONSchema, 以便序列化前可以精确计算长度
+type jsonSchema struct {
+	JSONSchema
+
+	open, sep, close  string // 整体的开头、cause 与 wrapper 之间、结尾: `{"cause":`、`,"wrapper":[`、`]}`
+	strOpen, strClose string // 非本包的 cause: `"`、`"`
+
+	code, msg, trace, caller, stack, fields, errors string // `"code":` 等
+}
+
+var (
+	defaultJSONSchema = compileJSONSchema(JSONSchema{})
+	curJSONSchema     atomic.Pointer[jsonSchema]
+)
+
+func init() {
+	curJSONSchema.Store(defaultJSONSchema)
+}
+
+// SetJSONSchema 设置全局的 JSON 输出格式, 对之后的 MarshalJSON、MarshalJSON2、AppendJSON、WriteJSON 生效
+func SetJSONSchema(s JSONSchema) {
+	curJSONSchema.Store(compileJSONSchema(s))
+}
+
+// GetJSONSchema 返回当前的 JSON 输出格式, 未设置的字段名已填充为默认值
+func GetJSONSchema() JSONSchema {
+	return curJSONSchema.Load().JSONSchema
+}
+
+func getJSONSchema() *jsonSchema {
+	return curJSONSchema.Load()
+}
+
+func compileJSONSchema(s JSONSchema) *jsonSchema {
+	for _, f := range []struct {
+		name *string
+		def  string
+	}{
+		{&s.Cause, "cause"},
+		{&s.Wrapper, "wrapper"},
+		{&s.Code, "code"},
+		{&s.Msg, "msg"},
+		{&s.Trace, "trace"},
+		{&s.Caller, "caller"},
+		{&s.Stack, "stack"},
+		{&s.Fields, "fields"},
+		{&s.Errors, "errors"},
+	} {
+		if *f.name == "" {
+			*f.name = f.def
+		}
+	}
+	key := func(name string) string {
+		return string(append(appendQuoteJSON(nil, name), ':'))
+	}
+	c := &jsonSchema{
+		JSONSchema: s,
+		code:       key(s.Code),
+		msg:        key(s.Msg),
+		trace:      key(s.Trace),
+		caller:     key(s.Caller),
+		stack:      key(s.Stack),
+		fields:     key(s.Fields),
+		errors:     key(s.Errors),
+	}
+	if s.Flat {
+		c.open, c.sep, c.close = "[", ",", "]"
+		c.strOpen, c.strClose = "{"+c.msg+`"`, `"}`
+	} else {
+		c.open, c.sep, c.close = "{"+key(s.Cause), ","+key(s.Wrapper)+"[", "]}"
+		c.strOpen, c.strClose = `"`, `"`
+	}
+	return c
+}
+
+// keep 值为 v 的 msg 或 trace 是否需要输出
+func (s *jsonSchema) keep(v string) bool {
+	return v != "" || !s.OmitEmpty
+}
+
+// stackSize 返回 appendStack 输出的长度, n 为 frame 个数, l 为所有 frame 转义后的总长度
+func (s *jsonSchema) stackSize(n, l int) int {
+	if s.StackString {
+		return len(`,""`) + len(s.stack) + l + (n-1)*len(`\n`)
+	}
+	return len(`,[]`) + len(s.stack) + l + n*len(`"",`) - len(`,`)
+}
+
+// appendStack 追加 `,"stack":["frame0","frame1"]` 或 `,"stack":"frame0\nframe1"`
+func (s *jsonSchema) appendStack(bs []byte, cs *callers, from, to int) []byte {
+	bs = append(bs, ',')
+	bs = append(bs, s.stack...)
+	if s.StackString {
+		bs = append(bs, '"')
+		for i, str := range cs.stack[from:to] {
+			if i != 0 {
+				bs = append(bs, `\n`...)
+			}
+			if !cs.needEscape(i + from) {
+				bs = append(bs, str...)
+			} else {
+				bs = appendEscape(bs, str)
+			}
+		}
+		return append(bs, '"')
+	}
+	bs = append(bs, '[')
+	for i, str := range cs.stack[from:to] {
+		if i != 0 {
+			bs = append(bs, ',')
+		}
+		bs = append(bs, '"')
+		if !cs.needEscape(i + from) {
+			bs = append(bs, str...)
+		} else {
+			bs = appendEscape(bs, str)
+		}
+		bs = append(bs, '"')
+	}
+	return append(bs, ']')
+}
@@ -0,0 +1,86 @@
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchema(t *testing.T) {
+	defer SetJSONSchema(JSONSchema{})
+
+	c := NewCode(0, errCode, errMsg).With("uid", 7)
+	err := WrapCode(WrapStack(c, errTrace), NewCode(-1, 2002, ""), "")
+
+	SetJSONSchema(JSONSchema{
+		Code:        "error.code",
+		Msg:         "error.message",
+		Stack:       "error.stack_trace",
+		Flat:        true,
+		StackString: true,
+		OmitEmpty:   true,
+	})
+	assert.Equal(t, "error.code", GetJSONSchema().Code)
+	assert.Equal(t, "trace", GetJSONSchema().Trace)
+
+	for name, bs := range map[string][]byte{
+		"MarshalJSON":  MarshalJSON(err),
+		"MarshalJSON2": MarshalJSON2(err),
+		"AppendJSON":   AppendJSON(nil, err),
+	} {
+		var layers []map[string]interface{}
+		if !assert.Nil(t, json.Unmarshal(bs, &layers), name+": "+string(bs)) {
+			continue
+		}
+		if !assert.Len(t, layers, 3, name) {
+			continue
+		}
+		assert.Equal(t, float64(errCode), layers[0]["error.code"], name)
+		assert.Equal(t, errMsg, layers[0]["error.message"], name)
+		assert.IsType(t, "", layers[0]["error.stack_trace"], name)
+		assert.Contains(t, layers[0]["error.stack_trace"], "TestJSONSchema", name)
+		assert.Equal(t, errTrace, layers[1]["trace"], name)
+		assert.IsType(t, "", layers[1]["error.stack_trace"], name)
+		assert.Equal(t, float64(2002), layers[2]["error.code"], name)
+		assert.NotContains(t, layers[2], "trace", name)
+		assert.Contains(t, layers[2], "caller", name)
+	}
+
+	t.Run("foreign", func(t *testing.T) {
+		var layers []map[string]interface{}
+		bs := MarshalJSON(Wrap(io.EOF, errTrace))
+		assert.Nil(t, json.Unmarshal(bs, &layers), string(bs))
+		assert.Equal(t, []interface{}{"EOF", errTrace}, []interface{}{layers[0]["error.message"], layers[1]["trace"]})
+
+		bs = MarshalJSON(io.EOF)
+		assert.Equal(t, `[{"error.message":"EOF"}]`, string(bs))
+	})
+
+	t.Run("join", func(t *testing.T) {
+		bs := MarshalJSON(Join(c, io.EOF))
+		assert.True(t, json.Valid(bs), string(bs))
+		assert.True(t, strings.HasPrefix(string(bs), `[{"errors":[[{"error.code":`), string(bs))
+	})
+
+	t.Run("size", func(t *testing.T) {
+		s := getJSONSchema()
+		fc := c.fmt()
+		buf := &writeBuffer{}
+		n := fc.jsonSize(s)
+		fc.json(buf, s)
+		assert.GreaterOrEqual(t, n, len(buf.buf))
+
+		fw := err.(*wrapper).Unwrap().(*wrapper).fmt()
+		buf = &writeBuffer{}
+		n = fw.jsonSize(s)
+		fw.json(buf, s)
+		assert.Equal(t, n, len(buf.buf))
+	})
+
+	SetJSONSchema(JSONSchema{Cause: "c", Wrapper: "w"})
+	bs := MarshalJSON(Wrap(io.EOF, errTrace))
+	assert.True(t, strings.HasPrefix(string(bs), `{"c":"EOF","w":[{"trace":`), string(bs))
+}
@@ -0,0 +1,209 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	siteBucket  = 5 * time.Second // 时间窗口的粒度
+	siteBuckets = 60              // 最多统计最近 siteBucket*siteBuckets 内的数量
+)
+
+var siteStatsEnabled atomic.Bool
+
+// EnableSiteStats 开启或关闭按调用位置、错误码统计生成的 *Code 数量, 默认不统计
+func EnableSiteStats(enable bool) {
+	siteStatsEnabled.Store(enable)
+}
+
+type siteKey struct {
+	site string // 生成 *Code 处的调用位置, 即 Stack() 的第一行
+	code int
+}
+
+// siteCounter 一个调用位置、错误码的计数; buckets 为环形的时间窗口, 并发写入时窗口内的数量是近似值
+type siteCounter struct {
+	total   atomic.Uint64
+	buckets [siteBuckets]struct {
+		epoch atomic.Int64
+		count atomic.Uint64
+	}
+}
+
+func (c *siteCounter) add(now int64) {
+	c.total.Add(1)
+	epoch := now / int64(siteBucket)
+	b := &c.buckets[epoch%siteBuckets]
+	if e := b.epoch.Load(); e != epoch && b.epoch.CompareAndSwap(e, epoch) {
+		b.count.Store(1)
+		return
+	}
+	b.count.Add(1)
+}
+
+// sum 返回最近 n 个 bucket 的数量
+func (c *siteCounter) sum(now int64, n int) (count uint64) {
+	epoch := now / int64(siteBucket)
+	for i := range c.buckets {
+		b := &c.buckets[i]
+		if e := b.epoch.Load(); e > epoch-int64(n) && e <= epoch {
+			count += b.count.Load()
+		}
+	}
+	return
+}
+
+// siteCounters 的上限由 SetCacheLimit 设置, 统计信息见 CacheStats
+var siteCounters = RCUCache[siteKey, *siteCounter]{
+	New: func(siteKey) *siteCounter {
+		return &siteCounter{}
+	},
+}
+
+// countSite 由 NewCode 等公开的构造函数调用, WrapStack、Join 只借用 *Code 记录调用栈, 不计入;
+// siteCounters 按 key 缓存计数器, 已有的调用位置只需一次无锁查询
+func countSite(c *Code) *Code {
+	if !siteStatsEnabled.Load() {
+		return c
+	}
+	if stack := c.Stack(); len(stack) > 0 {
+		siteCounters.Get(siteKey{site: stack[0], code: c.code}).add(time.Now().UnixNano())
+	}
+	return c
+}
+
+// ResetSiteStats 清空按调用位置的统计
+func ResetSiteStats() {
+	siteCounters.Reset()
+}
+
+// SiteStat 一个调用位置、错误码生成的 *Code 数量
+type SiteStat struct {
+	Site  string `json:"site"`
+	Code  int    `json:"code"`
+	Count uint64 `json:"count"`
+}
+
+// TopSites 返回生成 *Code 最多的 n 个调用位置及错误码, 按数量从多到少排列; n <= 0 时返回全部;
+// 需要先调用 EnableSiteStats(true)
+func TopSites(n int) []SiteStat {
+	return TopSitesWindow(n, 0)
+}
+
+// TopSitesWindow 同 TopSites, 但只统计最近 window 内生成的数量; window 按 5 秒向上取整, 最多 5 分钟,
+// window <= 0 时统计开启以来的总数
+func TopSitesWindow(n int, window time.Duration) (stats []SiteStat) {
+	now := time.Now().UnixNano()
+	buckets := int((window + siteBucket - 1) / siteBucket)
+	siteCounters.Range(func(k siteKey, c *siteCounter) bool {
+		count := c.total.Load()
+		if window > 0 {
+			count = c.sum(now, min(buckets, siteBuckets))
+		}
+		if count > 0 {
+			stats = append(stats, SiteStat{Site: k.site, Code: k.code, Count: count})
+		}
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Site != b.Site {
+			return a.Site < b.Site
+		}
+		return a.Code < b.Code
+	})
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return
+}
+
+// WriteTopSites 以表格的形式把 TopSitesWindow(n, window) 写入 w:
+//
+//	COUNT  CODE     SITE
+//	1024   1600002  (internal/user.go:42) user.(*Repo).Get
+func WriteTopSites(w io.Writer, n int, window time.Duration) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "COUNT\tCODE\tSITE")
+	for _, s := range TopSitesWindow(n, window) {
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", s.Count, s.Code, s.Site)
+	}
+	return tw.Flush()
+}
+
+// SiteStatsHandler 返回输出 TopSites 的 http.Handler, 与 /debug/pprof 类似默认输出文本表格, 如:
+//
+//	http.Handle("/debug/errors/sites", errors.SiteStatsHandler())
+//
+// 支持的参数: n 行数, 默认 100; window 时间窗口, 如 30s、5m, 默认为总数; format=json 输出 JSON
+func SiteStatsHandler() http.Handler {
+	return http.HandlerFunc(serveSiteStats)
+}
+
+func serveSiteStats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	n := 100
+	if s := q.Get("n"); s != "" {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil {
+			http.Error(w, "bad n: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var window time.Duration
+	if s := q.Get("window"); s != "" {
+		var err error
+		if window, err = time.ParseDuration(s); err != nil {
+			http.Error(w, "bad window: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if q.Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		stats := TopSitesWindow(n, window)
+		if stats == nil {
+			stats = []SiteStat{}
+		}
+		_ = json.NewEncoder(w).Encode(stats)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !siteStatsEnabled.Load() {
+		fmt.Fprintln(w, "# site stats are disabled, call errors.EnableSiteStats(true)")
+	}
+	_ = WriteTopSites(w, n, window)
+}
@@ -0,0 +1,107 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopSites(t *testing.T) {
+	EnableSiteStats(true)
+	defer EnableSiteStats(false)
+	ResetSiteStats()
+	defer ResetSiteStats()
+
+	f1 := func(code int) error { return NewCode(0, code, errMsg) }
+	f2 := func() error { return NewCode(0, errCode, errMsg) }
+	for i := 0; i < 5; i++ {
+		_ = f1(errCode)
+	}
+	for i := 0; i < 3; i++ {
+		_ = f2()
+	}
+	_ = f1(errCode + 1)
+
+	stats := TopSites(0)
+	if assert.Len(t, stats, 3) {
+		assert.Equal(t, uint64(5), stats[0].Count)
+		assert.Equal(t, errCode, stats[0].Code)
+		assert.Contains(t, stats[0].Site, "TestTopSites.func1")
+		assert.Equal(t, uint64(3), stats[1].Count)
+		assert.Contains(t, stats[1].Site, "TestTopSites.func2")
+		assert.Equal(t, uint64(1), stats[2].Count)
+		assert.Equal(t, errCode+1, stats[2].Code)
+		assert.Equal(t, stats[0].Site, stats[2].Site)
+	}
+	assert.Len(t, TopSites(1), 1)
+	assert.Equal(t, stats, TopSitesWindow(0, time.Minute))
+
+	t.Run("window", func(t *testing.T) {
+		c := &siteCounter{}
+		now := time.Now().UnixNano()
+		c.add(now - int64(2*time.Minute))
+		c.add(now - int64(time.Minute))
+		c.add(now)
+		c.add(now)
+		assert.Equal(t, uint64(4), c.total.Load())
+		assert.Equal(t, uint64(2), c.sum(now, 1))
+		assert.Equal(t, uint64(3), c.sum(now, 13))
+		assert.Equal(t, uint64(4), c.sum(now, siteBuckets))
+		c.add(now + int64(siteBucket*siteBuckets)) // 覆盖同一个 bucket
+		assert.Equal(t, uint64(1), c.sum(now+int64(siteBucket*siteBuckets), siteBuckets))
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		h := SiteStatsHandler()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/?n=2", nil))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if assert.Len(t, lines, 3) {
+			assert.Equal(t, []string{"COUNT", "CODE", "SITE"}, strings.Fields(lines[0]))
+			assert.True(t, strings.HasPrefix(lines[1], "5 "))
+		}
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/?format=json&window=1m", nil))
+		var got []SiteStat
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, stats, got)
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/?window=x", nil))
+		assert.Equal(t, 400, w.Code)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		ResetSiteStats()
+		SetCacheLimit(2, false)
+		defer SetCacheLimit(0, false)
+		for i := 0; i < 4; i++ {
+			_ = f1(errCode + i)
+		}
+		assert.Len(t, TopSites(0), 2)
+		s := siteCounters.Stat("siteCounters")
+		assert.Equal(t, uint64(2), s.Dropped)
+
+		ResetSiteStats()
+		assert.Equal(t, CacheStat{Name: "siteCounters"}, siteCounters.Stat("siteCounters"))
+	})
+
+	t.Run("wrap", func(t *testing.T) {
+		ResetSiteStats()
+		err := NewCode(0, errCode, errMsg)
+		_ = Wrap(err, "wrap")
+		_ = WrapStack(err, "wrap stack")
+		_ = Join(err, stderrors.New("other"))
+		stats := TopSites(0)
+		if assert.Len(t, stats, 1) {
+			assert.Equal(t, uint64(1), stats[0].Count)
+			assert.Equal(t, errCode, stats[0].Code)
+		}
+	})
+}
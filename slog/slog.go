@@ -10,42 +10,68 @@ import (
 	zlog "github.com/rs/zerolog"
 )
 
+// Option NewHandler、New、NewLoggerHandler 的选项
+type Option func(*options)
+
+type options struct {
+	mode errors.PathMode
+}
+
+// WithPathMode 设置 handler 输出 caller 时的文件路径格式, 默认 errors.PathDefault 表示跟随 errors.SetPathMode;
+// WithAttrs、WithGroup 派生的 handler 沿用该设置
+func WithPathMode(mode errors.PathMode) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+func newOptions(opts []Option) (o options) {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return
+}
+
 // handler 是一个自定义的 handler 包装器，用于跳过调用栈
 type handler struct {
 	slog.Handler
+	mode errors.PathMode
 }
 
-func toHandler(h slog.Handler) slog.Handler {
+func toHandler(h slog.Handler, mode errors.PathMode) slog.Handler {
 	if _, ok := h.(*handler); h == nil || ok {
 		return h
 	}
 
 	return &handler{
 		Handler: h,
+		mode:    mode,
 	}
 }
 
-func NewHandler(h slog.Handler) slog.Handler {
+func NewHandler(h slog.Handler, opts ...Option) slog.Handler {
 	if h == nil {
 		return nil
 	}
 	return &handler{
 		Handler: h,
+		mode:    newOptions(opts).mode,
 	}
 }
 
-func New(h slog.Handler) *slog.Logger {
+func New(h slog.Handler, opts ...Option) *slog.Logger {
 	if h == nil {
 		return slog.New(h)
 	}
 	return slog.New(&handler{
 		Handler: h,
+		mode:    newOptions(opts).mode,
 	})
 }
 
 func (h *handler) Handle(ctx context.Context, r slog.Record) error {
 	if r.PC == 0 {
-		f := errors.CallersSkip(4 - 2)[0]
+		f := errors.CallersSkipMode(4-2, h.mode)[0]
 		src := &slog.Source{
 			Function: f.Func,
 			File:     f.File,
@@ -54,7 +80,7 @@ func (h *handler) Handle(ctx context.Context, r slog.Record) error {
 		r.AddAttrs(slog.Attr{slog.SourceKey, slog.AnyValue(src)})
 		return h.Handler.Handle(ctx, r)
 	}
-	f := errors.CallerFrame(r.PC)
+	f := errors.CallerFrameMode(r.PC, h.mode)
 	src := &slog.Source{
 		Function: f.Func,
 		File:     f.File,
@@ -70,11 +96,11 @@ func (h *handler) Enabled(ctx context.Context, l slog.Level) bool {
 }
 
 func (h *handler) WithAttrs(as []slog.Attr) slog.Handler {
-	return toHandler(h.Handler.WithAttrs(as))
+	return toHandler(h.Handler.WithAttrs(as), h.mode)
 }
 
 func (h *handler) WithGroup(name string) slog.Handler {
-	return toHandler(h.Handler.WithGroup(name))
+	return toHandler(h.Handler.WithGroup(name), h.mode)
 }
 
 // handler 是一个自定义的 handler 包装器，用于跳过调用栈
@@ -82,11 +108,13 @@ type loggerHandler struct {
 	zerolog.Logger
 	prefix string // group prefix for nested groups
 	attrs  []slog.Attr
+	mode   errors.PathMode
 }
 
-func NewLoggerHandler(l zerolog.Logger) *loggerHandler {
+func NewLoggerHandler(l zerolog.Logger, opts ...Option) *loggerHandler {
 	return &loggerHandler{
 		Logger: l,
+		mode:   newOptions(opts).mode,
 	}
 }
 func (h *loggerHandler) Enabled(_ context.Context, level slog.Level) bool {
@@ -112,7 +140,7 @@ func (h *loggerHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	var src *slog.Source
 	if r.PC == 0 {
-		f := errors.CallersSkip(4 - 2)[0]
+		f := errors.CallersSkipMode(4-2, h.mode)[0]
 		src = &slog.Source{
 			Function: f.Func,
 			File:     f.File,
@@ -120,7 +148,7 @@ func (h *loggerHandler) Handle(ctx context.Context, r slog.Record) error {
 		}
 		event.Any(slog.SourceKey, src)
 	} else {
-		f := errors.CallerFrame(r.PC)
+		f := errors.CallerFrameMode(r.PC, h.mode)
 		src = &slog.Source{
 			Function: f.Func,
 			File:     f.File,
@@ -182,6 +210,7 @@ func (h *loggerHandler) clone() *loggerHandler {
 	h2 := &loggerHandler{
 		Logger: h.Logger,
 		prefix: h.prefix,
+		mode:   h.mode,
 	}
 	if len(h.attrs) > 0 {
 		h2.attrs = make([]slog.Attr, len(h.attrs))
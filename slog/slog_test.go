@@ -1,10 +1,15 @@
 package slog
 
 import (
+	"bytes"
 	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
+
+	"github.com/lxt1045/errors"
 )
 
 func TestNew(t *testing.T) {
@@ -87,3 +92,20 @@ func BenchmarkLog(b *testing.B) {
 		}
 	})
 }
+
+func TestPathMode(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	dir := file[:strings.LastIndexByte(file, '/')+1]
+
+	w1, w2 := &bytes.Buffer{}, &bytes.Buffer{}
+	full := New(slog.NewJSONHandler(w1, nil), WithPathMode(errors.PathFull))
+	def := New(slog.NewJSONHandler(w2, nil))
+	full.With("k", "v").Info("info")
+	def.Info("info")
+	if !strings.Contains(w1.String(), `"file":"`+dir) || !strings.Contains(w1.String(), `"k":"v"`) {
+		t.Fatal(w1.String())
+	}
+	if !strings.Contains(w2.String(), `"file":"slog/`) {
+		t.Fatal(w2.String())
+	}
+}
@@ -0,0 +1,343 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/binary"
+	stderrs "errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// ErrSnapshotMismatch ImportSnapshot 遇到其它可执行文件, 或全局 FrameFilter 不同时导出的快照时返回此错误
+var ErrSnapshotMismatch = stderrs.New("errors: snapshot is from a different binary or frame filter")
+
+const snapshotMagic = "LXTERRS2"
+
+var (
+	buildID     string
+	buildIDOnce sync.Once
+)
+
+// BuildID 返回当前可执行文件的 Go build ID, 读取不到时为可执行文件内容的 sha256; 都失败时为空
+func BuildID() string {
+	buildIDOnce.Do(func() {
+		if path, err := os.Executable(); err == nil {
+			buildID, _ = readBuildID(path)
+		}
+	})
+	return buildID
+}
+
+func readBuildID(path string) (string, error) {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		if s := f.Section(".note.go.buildid"); s != nil {
+			// note 格式: namesz(4) descsz(4) type(4) name("Go\0\0") desc
+			if data, err := s.Data(); err == nil && len(data) >= 16 {
+				if n := int(f.ByteOrder.Uint32(data[4:8])); 16+n <= len(data) {
+					return string(data[16 : 16+n]), nil
+				}
+			}
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	const prefix = "\xff Go build ID: \""
+	if i := bytes.Index(data, []byte(prefix)); i >= 0 {
+		if j := bytes.IndexByte(data[i+len(prefix):], '"'); j >= 0 {
+			return string(data[i+len(prefix) : i+len(prefix)+j]), nil
+		}
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// pcBase 快照中的 pc 都保存为相对于它的偏移, 以兼容 PIE 等每次加载地址不同的情况
+var pcBase = reflect.ValueOf(BuildID).Pointer()
+
+// globalFilterID 返回全局 FrameFilter 的 id, 没有时为 0;
+// id 按 NewFrameFilter 的调用顺序分配, 同一个可执行文件按相同顺序初始化时相同
+func globalFilterID() uintptr {
+	if f := GetFrameFilter(); f != nil {
+		return f.id
+	}
+	return 0
+}
+
+// ExportSnapshot 把 cacheStack、cacheWrapper 中已解析的调用栈导出到 w, 同一个可执行文件的下一次运行可以用
+// ImportSnapshot 导入, 从而避免部署后每个调用位置第一次生成 error 时解析调用栈的开销;
+// 快照记录了全局 FrameFilter 的 id, 只导出不使用 FrameFilter 或使用全局 FrameFilter 的调用栈
+func ExportSnapshot(w io.Writer) error {
+	id := BuildID()
+	if id == "" {
+		return fmt.Errorf("errors: unknown build id")
+	}
+	var (
+		bs       = []byte(snapshotMagic)
+		strs     []string
+		strIdx   = map[string]uint64{}
+		records  []byte
+		filterID = globalFilterID()
+	)
+	bs = binary.AppendUvarint(bs, uint64(len(id)))
+	bs = append(bs, id...)
+	bs = binary.AppendUvarint(bs, uint64(filterID))
+	str := func(s string) uint64 {
+		i, ok := strIdx[s]
+		if !ok {
+			i = uint64(len(strs))
+			strIdx[s] = i
+			strs = append(strs, s)
+		}
+		return i
+	}
+	appendPCs := func(bs []byte, pcs []uintptr) []byte {
+		bs = binary.AppendUvarint(bs, uint64(len(pcs)))
+		for _, pc := range pcs {
+			bs = binary.AppendVarint(bs, int64(pc-pcBase))
+		}
+		return bs
+	}
+
+	nStacks := 0
+	cacheStack.Range(func(k string, cs *callers) bool {
+		key := unsafe.Slice((*uintptr)(unsafe.Pointer(unsafe.StringData(k))), len(k)/int(unsafe.Sizeof(uintptr(0))))
+		// 不带 FrameFilter 的 key 为 pcs + mode, 带的为 pcs + mode + filter id + skip
+		n := len(key) - 1
+		if cs.filter != 0 {
+			if cs.filter != filterID {
+				return true // 其它 FrameFilter 在下一次运行时无法对应
+			}
+			n = len(key) - 3
+		}
+		if n <= 0 {
+			return true
+		}
+		records = binary.AppendVarint(records, int64(PathMode(key[n])))
+		records = binary.AppendUvarint(records, uint64(cs.filter))
+		if cs.filter != 0 {
+			records = binary.AppendUvarint(records, uint64(key[n+2]))
+		}
+		records = appendPCs(records, key[:n])
+		records = binary.AppendUvarint(records, uint64(len(cs.stack)))
+		for _, s := range cs.stack {
+			records = binary.AppendUvarint(records, str(s))
+		}
+		records = appendPCs(records, cs.pcs)
+		nStacks++
+		return true
+	})
+
+	type wrapperKey struct {
+		pc   uintptr
+		mode PathMode
+	}
+	frames := map[wrapperKey]*frame{}
+	cacheWrapper.Range(func(k [2]uintptr, f *frame) bool {
+		if runtime.FuncForPC(k[0]) != nil {
+			frames[wrapperKey{k[0], PathMode(k[1])}] = f
+		}
+		return true
+	})
+	records = binary.AppendUvarint(records, uint64(len(frames)))
+	for k, f := range frames {
+		records = binary.AppendVarint(records, int64(k.pc-pcBase))
+		records = binary.AppendVarint(records, int64(k.mode))
+		records = binary.AppendUvarint(records, str(f.stack))
+	}
+
+	bs = binary.AppendUvarint(bs, uint64(len(strs)))
+	for _, s := range strs {
+		bs = binary.AppendUvarint(bs, uint64(len(s)))
+		bs = append(bs, s...)
+	}
+	bs = binary.AppendUvarint(bs, uint64(nStacks))
+	bs = append(bs, records...)
+	_, err := w.Write(bs)
+	return err
+}
+
+// ImportSnapshot 导入 ExportSnapshot 导出的快照, 返回导入的调用栈数量;
+// 快照来自其它可执行文件, 或导出时的全局 FrameFilter 与当前不同时返回 ErrSnapshotMismatch, 不会导入任何数据;
+// 读取完整个快照后一次性写入缓存
+func ImportSnapshot(r io.Reader) (n int, err error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err = io.ReadFull(br, magic); err != nil || string(magic) != snapshotMagic {
+		return 0, fmt.Errorf("errors: invalid snapshot")
+	}
+	id, err := readSnapshotString(br)
+	if err != nil {
+		return 0, err
+	}
+	if self := BuildID(); self == "" || id != self {
+		return 0, ErrSnapshotMismatch
+	}
+	filterID, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, err
+	}
+	if uintptr(filterID) != globalFilterID() {
+		return 0, ErrSnapshotMismatch
+	}
+
+	nStrs, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, err
+	}
+	strs := make([]string, 0, min(nStrs, 1<<16))
+	for i := uint64(0); i < nStrs; i++ {
+		s, err := readSnapshotString(br)
+		if err != nil {
+			return 0, err
+		}
+		strs = append(strs, s)
+	}
+	readStr := func() (string, error) {
+		i, err := binary.ReadUvarint(br)
+		if err != nil {
+			return "", err
+		}
+		if i >= uint64(len(strs)) {
+			return "", fmt.Errorf("errors: invalid snapshot string index %d", i)
+		}
+		return strs[i], nil
+	}
+	readPCs := func() ([]uintptr, error) {
+		l, err := binary.ReadUvarint(br)
+		if err != nil || l == 0 {
+			return nil, err
+		}
+		pcs := make([]uintptr, 0, min(l, DefaultDepth*4))
+		for j := uint64(0); j < l; j++ {
+			off, err := binary.ReadVarint(br)
+			if err != nil {
+				return nil, err
+			}
+			pcs = append(pcs, pcBase+uintptr(off))
+		}
+		return pcs, nil
+	}
+
+	nStacks, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, err
+	}
+	stacks := make(map[string]*callers, min(nStacks, 1<<16))
+	for i := uint64(0); i < nStacks; i++ {
+		mode, err := binary.ReadVarint(br)
+		if err != nil {
+			return 0, err
+		}
+		fid, err := binary.ReadUvarint(br)
+		if err != nil {
+			return 0, err
+		}
+		var skip uint64
+		if fid != 0 {
+			if fid != filterID {
+				return 0, fmt.Errorf("errors: invalid snapshot frame filter %d", fid)
+			}
+			if skip, err = binary.ReadUvarint(br); err != nil {
+				return 0, err
+			}
+		}
+		key, err := readPCs()
+		if err != nil {
+			return 0, err
+		}
+		l, err := binary.ReadUvarint(br)
+		if err != nil {
+			return 0, err
+		}
+		stack := make([]string, 0, min(l, DefaultDepth*4))
+		for j := uint64(0); j < l; j++ {
+			s, err := readStr()
+			if err != nil {
+				return 0, err
+			}
+			stack = append(stack, s)
+		}
+		pcs, err := readPCs()
+		if err != nil {
+			return 0, err
+		}
+		key = append(key, uintptr(PathMode(mode)))
+		if fid != 0 {
+			key = append(key, uintptr(fid), uintptr(skip))
+		}
+		cs := newCallers(stack)
+		cs.pcs, cs.filter = pcs, uintptr(fid)
+		stacks[pcsToNewStr(key)] = cs
+	}
+
+	nFrames, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, err
+	}
+	frames := make(map[[2]uintptr]*frame, min(nFrames, 1<<16))
+	for i := uint64(0); i < nFrames; i++ {
+		off, err := binary.ReadVarint(br)
+		if err != nil {
+			return 0, err
+		}
+		m, err := binary.ReadVarint(br)
+		if err != nil {
+			return 0, err
+		}
+		s, err := readStr()
+		if err != nil {
+			return 0, err
+		}
+		frames[[2]uintptr{pcBase + uintptr(off), uintptr(m)}] = newFrame(s)
+	}
+	n = cacheStack.merge(stacks)
+	cacheWrapper.merge(frames)
+	return n, nil
+}
+
+func readSnapshotString(br *bufio.Reader) (string, error) {
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		return "", err
+	}
+	if l > 1<<20 {
+		return "", fmt.Errorf("errors: invalid snapshot string length %d", l)
+	}
+	bs := make([]byte, l)
+	if _, err = io.ReadFull(br, bs); err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
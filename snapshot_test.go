@@ -0,0 +1,90 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	assert.NotEmpty(t, BuildID())
+
+	oldStack, oldWrapper := cacheStack.cache, cacheWrapper.cache
+	defer func() {
+		cacheStack.cache, cacheWrapper.cache = oldStack, oldWrapper
+	}()
+
+	f := func() error { return Wrap(NewCode(0, errCode, errMsg), errTrace) }
+	var want string
+	for i := 0; i < 2; i++ {
+		misses, wrapperMisses := cacheStack.counter.misses.Load(), cacheWrapper.counter.misses.Load()
+		err := f()
+		if i == 0 {
+			want = string(MarshalText(err))
+			buf := &bytes.Buffer{}
+			assert.Nil(t, ExportSnapshot(buf))
+
+			// 清空缓存后导入
+			cacheStack.cache, cacheWrapper.cache = nil, nil
+			n, err := ImportSnapshot(buf)
+			assert.Nil(t, err)
+			assert.Greater(t, n, 0)
+			assert.Greater(t, cacheWrapper.Len(), 0)
+			continue
+		}
+		assert.Equal(t, want, string(MarshalText(err)))
+		assert.Equal(t, misses, cacheStack.counter.misses.Load(), "should hit the imported cache")
+		assert.Equal(t, wrapperMisses, cacheWrapper.counter.misses.Load(), "should hit the imported cache")
+	}
+
+	t.Run("filter", func(t *testing.T) {
+		defer SetFrameFilter(GetFrameFilter())
+		filter := NewFrameFilter(func(fn, file string, line int) bool { return strings.HasPrefix(fn, "testing.") })
+		SetFrameFilter(filter)
+		f := func() error { return NewCode(0, errCode, errMsg) }
+		var want string
+		var bs []byte
+		for i := 0; i < 2; i++ {
+			misses := cacheStack.counter.misses.Load()
+			err := f()
+			if i == 1 {
+				assert.Equal(t, want, string(MarshalText(err)))
+				assert.Equal(t, misses, cacheStack.counter.misses.Load(), "should hit the imported cache")
+				break
+			}
+			want = string(MarshalText(err))
+			buf := &bytes.Buffer{}
+			assert.Nil(t, ExportSnapshot(buf))
+			bs = buf.Bytes()
+
+			cacheStack.cache = nil
+			copies := cacheStack.counter.copies.Load()
+			n, err := ImportSnapshot(bytes.NewReader(bs))
+			assert.Nil(t, err)
+			assert.Greater(t, n, 0)
+			assert.Equal(t, copies+1, cacheStack.counter.copies.Load(), "import should swap the map once")
+		}
+
+		// 全局 FrameFilter 不同时拒绝导入
+		SetFrameFilter(NewFrameFilter(filter.skips...))
+		_, err := ImportSnapshot(bytes.NewReader(bs))
+		assert.ErrorIs(t, err, ErrSnapshotMismatch)
+		SetFrameFilter(nil)
+		_, err = ImportSnapshot(bytes.NewReader(bs))
+		assert.ErrorIs(t, err, ErrSnapshotMismatch)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		bs := []byte(snapshotMagic)
+		bs = binary.AppendUvarint(bs, 5)
+		bs = append(bs, "other"...)
+		_, err := ImportSnapshot(bytes.NewReader(bs))
+		assert.ErrorIs(t, err, ErrSnapshotMismatch)
+
+		_, err = ImportSnapshot(bytes.NewReader([]byte("bad")))
+		assert.NotNil(t, err)
+	})
+}
@@ -40,27 +40,41 @@ func buildStackSlow(s []uintptr) int {
 }
 
 func CallersSkip(skip int) (cs []caller) {
-	pcs := pool.Get().(*[DefaultDepth]uintptr)
-	for i := range pcs {
-		pcs[i] = 0
+	return callersSkip(skip, GetPathMode(), GetFrameFilter())
+}
+
+// CallersSkipMode 同 CallersSkip, 但使用指定的路径格式, 供日志适配器使用
+func CallersSkipMode(skip int, mode PathMode) (cs []caller) {
+	return callersSkip(skip, mode.Resolve(), GetFrameFilter())
+}
+
+// CallersSkip 同 errors.CallersSkip, 但使用 f 过滤调用栈, 而不是全局的 FrameFilter
+func (f *FrameFilter) CallersSkip(skip int) (cs []caller) {
+	return callersSkip(skip, GetPathMode(), f)
+}
+
+// callersSkip 只能被 CallersSkip 等直接调用
+func callersSkip(skip int, mode PathMode, filter *FrameFilter) (cs []caller) {
+	if skip < 0 {
+		skip = 0
 	}
-	n := buildStack(pcs[:]) //仅当特征码使用，有点大材小用
-	// for i := n; i < DefaultDepth; i++ {
-	// 	pcs[i] = 0
-	// }
+	depth := StackDepth()
+	p, pcs := getPCs(depth + 3)
+	n := buildStack(pcs[:depth:depth]) //仅当特征码使用，有点大材小用
+	key := stackKey(pcs, n, mode, filter, skip)
 
 	//
-	cs = cacheCallers.Get(pcs, n)
+	cs = cacheCallers.GetPCs(key)
 	if cs == nil {
-		pcs1 := make([]uintptr, DefaultDepth)
-		npc1 := runtime.Callers(baseSkip, pcs1[:DefaultDepth])
-		cs = parseSlow(pcs1[:npc1])
+		pcs1 := make([]uintptr, depth)
+		npc1 := runtime.Callers(baseSkip+1, pcs1)
+		cs, _ = parseFrames(skipPCs(pcs1[:npc1], filter, skip), mode, filter)
 
-		cacheCallers.Set(pcs, n, cs)
+		cacheCallers.SetPCs(key, cs)
 	}
-	pool.Put(pcs)
-	if skip < 0 {
-		skip = 0
+	pool.Put(p)
+	if filter != nil {
+		skip = 0 // 已经在过滤之前处理了 skip
 	}
 	if skip >= len(cs) {
 		return nil
@@ -72,19 +86,30 @@ func CallersSkip(skip int) (cs []caller) {
 
 // CallerFrame 使用 Read-copy update(RCU) 缓存提高性能
 func CallerFrame(l uintptr) (c *caller) {
-	c = cacheCaller.Get(l)
+	return CallerFrameMode(l, GetPathMode())
+}
+
+// CallerFrameMode 同 CallerFrame, 但使用指定的路径格式, 供日志适配器使用
+func CallerFrameMode(l uintptr, mode PathMode) (c *caller) {
+	key := callerKey{pc: l, mode: mode.Resolve()}
+	c = cacheCaller.Get(key)
 	if c != nil {
 		return
 	}
 
-	cs := parseSlow([]uintptr{l})
+	cs, _ := parseFrames([]uintptr{l}, key.mode, nil)
 	if len(cs) > 0 {
 		c = &cs[0]
-		cacheCaller.Set(l, c)
+		cacheCaller.Set(key, c)
 	}
 	return
 }
 
+type callerKey struct {
+	pc   uintptr
+	mode PathMode
+}
+
 type zeroStack struct {
 	stack []caller
 }
@@ -164,3 +189,7 @@ type PC uintptr
 func (p PC) CallerFrame() (c *caller) {
 	return CallerFrame(uintptr(p))
 }
+
+func (p PC) CallerFrameMode(mode PathMode) (c *caller) {
+	return CallerFrameMode(uintptr(p), mode)
+}
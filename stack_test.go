@@ -106,32 +106,32 @@ func BenchmarkCallersSkip(b *testing.B) {
 	b.Run("runtime.Callers", func(b *testing.B) {
 		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
-			pcs := pool.Get().(*[DefaultDepth]uintptr)
+			p, pcs := getPCs(DefaultDepth)
 			func() {
 				func() {
 					func() {
-						runtime.Callers(baseSkip, pcs[:DefaultDepth])
+						runtime.Callers(baseSkip, pcs)
 						// parseSlow(pcs[:n])
 					}()
 				}()
 			}()
-			pool.Put(pcs)
+			pool.Put(p)
 		}
 		b.StopTimer()
 	})
 	b.Run("runtime.Callers & runtime.CallersFrames", func(b *testing.B) {
 		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
-			pcs := pool.Get().(*[DefaultDepth]uintptr)
+			p, pcs := getPCs(DefaultDepth)
 			func() {
 				func() {
 					func() {
-						n := runtime.Callers(baseSkip, pcs[:DefaultDepth])
+						n := runtime.Callers(baseSkip, pcs)
 						parseSlow(pcs[:n])
 					}()
 				}()
 			}()
-			pool.Put(pcs)
+			pool.Put(p)
 		}
 		b.StopTimer()
 	})
@@ -0,0 +1,91 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"runtime"
+
+	pkgerrs "github.com/pkg/errors"
+)
+
+// Frame 即 github.com/pkg/errors 的 Frame: 值为 pc+1(即 runtime.Callers 返回的返回地址);
+// 使用别名而不是复制, 格式化结果与 pkg/errors 完全一致
+type Frame = pkgerrs.Frame
+
+// StackTrace 即 github.com/pkg/errors 的 StackTrace; 因为类型相同, 本包的 error 满足 pkg/errors 的
+// stackTracer 接口 (interface{ StackTrace() errors.StackTrace }), Sentry 等依赖该接口的库可以直接使用
+type StackTrace = pkgerrs.StackTrace
+
+func toStackTrace(pcs []uintptr) StackTrace {
+	if len(pcs) == 0 {
+		return nil
+	}
+	st := make(StackTrace, len(pcs))
+	for i, pc := range pcs {
+		st[i] = Frame(pc)
+	}
+	return st
+}
+
+// StackPCs 返回与 Stack() 一一对应的 pc(返回地址), 可以交给 runtime.CallersFrames 解析;
+// 由 NewCodeWithStack、NewRemoteCode、ParseJSON 等生成的 *Code 没有 pc, 返回 nil
+func (e *Code) StackPCs() []uintptr {
+	if e.cache == nil || len(e.cache.pcs) <= e.skip {
+		return nil
+	}
+	return e.cache.pcs[e.skip:]
+}
+
+// Frames 返回调用栈的 runtime.Frames 迭代器
+func (e *Code) Frames() *runtime.Frames {
+	return runtime.CallersFrames(e.StackPCs())
+}
+
+// StackTrace 兼容 pkg/errors 的 stackTracer 接口
+func (e *Code) StackTrace() StackTrace {
+	return toStackTrace(e.StackPCs())
+}
+
+// StackPCs 返回调用 Wrap 处的 pc, WrapStack 生成的返回完整调用栈的 pc; ParseJSON 还原的 wrapper 返回 nil
+func (e *wrapper) StackPCs() []uintptr {
+	if e.cache != nil {
+		if len(e.cache.pcs) <= e.skip {
+			return nil
+		}
+		return e.cache.pcs[e.skip:]
+	}
+	if e.parsed != nil || e.pc[0] == 0 {
+		return nil
+	}
+	return e.pc[:]
+}
+
+// Frames 返回调用 Wrap 处的 runtime.Frames 迭代器
+func (e *wrapper) Frames() *runtime.Frames {
+	return runtime.CallersFrames(e.StackPCs())
+}
+
+// StackTrace 兼容 pkg/errors 的 stackTracer 接口, Wrap 生成的只包含调用 Wrap 处的一个 frame
+func (e *wrapper) StackTrace() StackTrace {
+	return toStackTrace(e.StackPCs())
+}
@@ -0,0 +1,79 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	pkgerrs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStackPCs(t *testing.T) {
+	t.Run("Code", func(t *testing.T) {
+		pcs := [DefaultDepth]uintptr{}
+		npc, e := runtime.Callers(1, pcs[:]), NewCode(0, errCode, errMsg)
+		got := e.StackPCs()
+		assert.Equal(t, len(e.Stack()), len(got))
+		assert.Equal(t, pcs[1:len(got)], got[1:])
+
+		i, frames := 0, e.Frames()
+		for {
+			f, more := frames.Next()
+			assert.Equal(t, e.Stack()[i], toCaller(f).String())
+			i++
+			if !more {
+				break
+			}
+		}
+		assert.Equal(t, len(got), i)
+		assert.True(t, npc >= i)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		var e *Code
+		func() {
+			e = NewCode(1, errCode, errMsg)
+		}()
+		assert.Equal(t, len(e.Stack()), len(e.StackPCs()))
+		f, _ := e.Frames().Next()
+		assert.Equal(t, e.Stack()[0], toCaller(f).String())
+	})
+
+	t.Run("no pcs", func(t *testing.T) {
+		e := NewCodeWithStack(errCode, errMsg, []string{testFrameFunc}).(*Code)
+		assert.Nil(t, e.StackPCs())
+		assert.Nil(t, e.StackTrace())
+		assert.Nil(t, NewCode(-1, errCode, errMsg).StackPCs())
+	})
+
+	t.Run("wrapper", func(t *testing.T) {
+		pcs := [1]uintptr{}
+		runtime.Callers(1, pcs[:])
+		err := Wrap(NewCode(0, errCode, errMsg), errTrace)
+		w := err.(*wrapper)
+		assert.Equal(t, 1, len(w.StackPCs()))
+		f, _ := w.Frames().Next()
+		f1, _ := runtime.CallersFrames(pcs[:]).Next()
+		assert.Equal(t, f1.Function, f.Function)
+		assert.Equal(t, w.parse().stack, toCaller(f).String())
+	})
+}
+
+func TestStackTrace(t *testing.T) {
+	e := NewCode(0, errCode, errMsg)
+	st := e.StackTrace()
+	assert.Equal(t, len(e.Stack()), len(st))
+
+	assert.Equal(t, "stacktrace_test.go", fmt.Sprintf("%s", st[0]))
+	assert.Equal(t, "TestStackTrace", fmt.Sprintf("%n", st[0]))
+
+	// 满足 pkg/errors 的 stackTracer 接口
+	type stackTracer interface{ StackTrace() pkgerrs.StackTrace }
+	_, ok := error(e).(stackTracer)
+	assert.True(t, ok)
+	w, ok := Wrap(e, errTrace).(stackTracer)
+	if assert.True(t, ok) {
+		assert.Equal(t, 1, len(w.StackTrace()))
+	}
+}
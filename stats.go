@@ -0,0 +1,266 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"text/tabwriter"
+	"unsafe"
+)
+
+var cacheStatsEnabled atomic.Bool
+
+// EnableCacheStats 开启或关闭命中次数的统计; 命中在热路径上, 因此默认不统计, 其它计数始终有效
+func EnableCacheStats(enable bool) {
+	cacheStatsEnabled.Store(enable)
+}
+
+// cacheCounter 内部 cache 的计数, 见 CacheStats
+type cacheCounter struct {
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	copies  atomic.Uint64
+	evicted atomic.Uint64
+	dropped atomic.Uint64
+}
+
+func (c *cacheCounter) hit() {
+	if cacheStatsEnabled.Load() {
+		c.hits.Add(1)
+	}
+}
+
+func (c *cacheCounter) reset() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.copies.Store(0)
+	c.evicted.Store(0)
+	c.dropped.Store(0)
+}
+
+func (c *cacheCounter) count(hit bool) {
+	if hit {
+		c.hit()
+		return
+	}
+	c.misses.Add(1)
+}
+
+// CacheStat 一个内部 cache 的统计信息
+type CacheStat struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"` // 仅统计 EnableCacheStats(true) 之后的命中
+	Misses  uint64 `json:"misses"`
+	Copies  uint64 `json:"copies"`  // 重建只读 map 的次数
+	Evicted uint64 `json:"evicted"` // 有界模式下被淘汰的 key 数量
+	Dropped uint64 `json:"dropped"` // 有界模式下因为已满而没有缓存的 key 数量
+	Bytes   int    `json:"bytes"`   // 估算的内存占用
+}
+
+// Stat 返回 c 的统计信息, 需要遍历所有 key 估算内存占用, 不适合频繁调用
+func (c *RCUCache[K, V]) Stat(name string) (s CacheStat) {
+	s = CacheStat{
+		Name:    name,
+		Hits:    c.counter.hits.Load(),
+		Misses:  c.counter.misses.Load(),
+		Copies:  c.counter.copies.Load(),
+		Evicted: c.counter.evicted.Load(),
+		Dropped: c.counter.dropped.Load(),
+	}
+	var k K
+	var v V
+	entry := int(unsafe.Sizeof(k)+unsafe.Sizeof(v)) + 8 // 8 为 map 每个 entry 的大致额外开销
+	c.Range(func(k K, v V) bool {
+		s.Entries++
+		s.Bytes += entry + entrySize(k, v)
+		return true
+	})
+	return
+}
+
+// Range 遍历所有缓存的 key, f 返回 false 时停止; 遍历的是调用时的快照
+func (c *RCUCache[K, V]) Range(f func(K, V) bool) {
+	var cache map[K]V
+	if p := atomic.LoadPointer(&c.cache); p != nil {
+		cache = *(*map[K]V)(p)
+	}
+	c.mu.Lock()
+	overflow := make(map[K]V, len(c.overflow))
+	for k, v := range c.overflow {
+		overflow[k] = v
+	}
+	c.mu.Unlock()
+
+	for k, v := range overflow {
+		if !f(k, v) {
+			return
+		}
+	}
+	for k, v := range cache {
+		if _, ok := overflow[k]; ok {
+			continue
+		}
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// entrySize 估算 key、value 引用的内存
+func entrySize(k, v interface{}) (n int) {
+	switch x := k.(type) {
+	case string:
+		n += len(x)
+	case siteKey:
+		n += len(x.site)
+	}
+	switch x := v.(type) {
+	case *callers:
+		n += int(unsafe.Sizeof(*x)) + (len(x.escape)+len(x.pcs))*8
+		for _, s := range x.stack {
+			n += int(unsafe.Sizeof(s)) + len(s)
+		}
+	case []caller:
+		for _, c := range x {
+			n += int(unsafe.Sizeof(c)) + len(c.FileLine) + len(c.Func) + len(c.File)
+		}
+	case *caller:
+		n += int(unsafe.Sizeof(*x)) + len(x.FileLine) + len(x.Func) + len(x.File)
+	case *frame:
+		n += int(unsafe.Sizeof(*x)) + len(x.stack)
+	case *siteCounter:
+		n += int(unsafe.Sizeof(*x))
+	}
+	return
+}
+
+// CacheStats 返回本包内部各个 cache 的统计信息
+func CacheStats() []CacheStat {
+	return []CacheStat{
+		cacheStack.Stat("cacheStack"),
+		cacheCallers.Stat("cacheCallers"),
+		cacheCaller.Stat("cacheCaller"),
+		cacheWrapper.Stat("cacheWrapper"),
+		cacheSite.Stat("cacheSite"),
+		siteCounters.Stat("siteCounters"),
+	}
+}
+
+// PublishCacheStats 把 CacheStats 以 name 发布到 expvar, 可以通过 /debug/vars 查看; 同一个 name 只能发布一次
+func PublishCacheStats(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return CacheStats()
+	}))
+}
+
+type callSite struct {
+	cache   string
+	site    string
+	entries int
+	frames  int
+}
+
+// WriteCallSites 以表格的形式把各个 cache 中不同的调用位置写入 w, 按 entries 从多到少排列:
+//
+//	CACHE         ENTRIES  FRAMES  SITE
+//	cacheStack    3        6       (internal/user.go:42) user.(*Repo).Get
+//
+// entries 为同一调用位置在 cache 中的 key 数量 (不同的调用栈、路径格式等), 可以用于定位 cache 膨胀的来源
+func WriteCallSites(w io.Writer) error {
+	var sites []*callSite
+	add := func(cache string) func(v interface{}) {
+		m := map[string]*callSite{}
+		return func(v interface{}) {
+			site, frames := siteOf(v)
+			s := m[site]
+			if s == nil {
+				s = &callSite{cache: cache, site: site}
+				m[site] = s
+				sites = append(sites, s)
+			}
+			s.entries++
+			s.frames = max(s.frames, frames)
+		}
+	}
+	f := add("cacheStack")
+	cacheStack.Range(func(_ string, v *callers) bool { f(v); return true })
+	f = add("cacheCallers")
+	cacheCallers.Range(func(_ string, v []caller) bool { f(v); return true })
+	f = add("cacheCaller")
+	cacheCaller.Range(func(_ callerKey, v *caller) bool { f(v); return true })
+	f = add("cacheWrapper")
+	cacheWrapper.Range(func(_ [2]uintptr, v *frame) bool { f(v); return true })
+	f = add("cacheSite")
+	cacheSite.Range(func(pc uintptr, _ uint64) bool { f(pc); return true })
+
+	order := map[string]int{"cacheStack": 0, "cacheCallers": 1, "cacheCaller": 2, "cacheWrapper": 3, "cacheSite": 4}
+	sort.Slice(sites, func(i, j int) bool {
+		a, b := sites[i], sites[j]
+		if a.cache != b.cache {
+			return order[a.cache] < order[b.cache]
+		}
+		if a.entries != b.entries {
+			return a.entries > b.entries
+		}
+		return a.site < b.site
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CACHE\tENTRIES\tFRAMES\tSITE")
+	for _, s := range sites {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", s.cache, s.entries, s.frames, s.site)
+	}
+	return tw.Flush()
+}
+
+// siteOf 返回缓存的 value 对应的调用位置和 frame 数量
+func siteOf(v interface{}) (site string, frames int) {
+	switch x := v.(type) {
+	case *callers:
+		if x != nil && len(x.stack) > 0 {
+			return x.stack[0], len(x.stack)
+		}
+	case []caller:
+		if len(x) > 0 {
+			return x[0].String(), len(x)
+		}
+	case *caller:
+		if x != nil {
+			return x.String(), 1
+		}
+	case *frame:
+		if x != nil {
+			return x.stack, 1
+		}
+	case uintptr:
+		f, _ := runtime.CallersFrames([]uintptr{x}).Next()
+		return toCaller(f).String(), 1
+	}
+	return "", 0
+}
@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheStats(t *testing.T) {
+	EnableCacheStats(true)
+	defer EnableCacheStats(false)
+
+	f := func() error { return Wrap(NewCode(0, errCode, errMsg), errTrace) }
+	for i := 0; i < 3; i++ {
+		_ = MarshalJSON(f())
+	}
+
+	stats := CacheStats()
+	m := map[string]CacheStat{}
+	for _, s := range stats {
+		m[s.Name] = s
+	}
+	assert.Len(t, m, 6)
+	s := m["cacheStack"]
+	assert.Greater(t, s.Entries, 0)
+	assert.Greater(t, s.Hits, uint64(0))
+	assert.Greater(t, s.Misses, uint64(0))
+	assert.Greater(t, s.Copies, uint64(0))
+	assert.Greater(t, s.Bytes, s.Entries*len("(file:1) f"))
+	assert.Greater(t, m["cacheWrapper"].Entries, 0)
+
+	t.Run("expvar", func(t *testing.T) {
+		PublishCacheStats("errors.test.cache")
+		var got []CacheStat
+		assert.Nil(t, json.Unmarshal([]byte(expvar.Get("errors.test.cache").String()), &got))
+		assert.Len(t, got, len(stats))
+	})
+
+	t.Run("WriteCallSites", func(t *testing.T) {
+		Fingerprint(Wrap(io.EOF, errTrace))
+		buf := &bytes.Buffer{}
+		assert.Nil(t, WriteCallSites(buf))
+		assert.Contains(t, buf.String(), "cacheSite ")
+		lines := strings.Split(buf.String(), "\n")
+		assert.True(t, strings.HasPrefix(lines[0], "CACHE "), lines[0])
+		assert.Contains(t, buf.String(), "TestCacheStats.func1")
+		t.Log("\n" + buf.String())
+	})
+
+	t.Run("RCUCache.Stat", func(t *testing.T) {
+		c := RCUCache[string, int]{}
+		c.SetLimit(10, false)
+		for _, k := range []string{"a", "b", "c"} {
+			c.Set(k, 1)
+		}
+		c.Get("a")
+		c.Get("x")
+		s := c.Stat("test")
+		assert.Equal(t, CacheStat{Name: "test", Entries: 3, Hits: 1, Misses: 1, Bytes: s.Bytes}, s)
+	})
+}
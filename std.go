@@ -9,26 +9,40 @@ const (
 	DefaultMsg  = ""
 )
 
-// Is 检查code是不是一样的
+// Is 检查code是不是一样的; 遇到 Join 等多错误时会检查所有分支
 func Is(err1, target error) bool {
-	e1, ok := err1.(*Code)
-	if !ok || e1.Code() == DefaultCode {
-		return stderrs.Is(err1, target)
+	switch e1 := err1.(type) {
+	case *Code:
+		if e1.Code() == DefaultCode {
+			break
+		}
+		return e1.Is(target) || (e1.err != nil && Is(e1.err, target))
+	case multiError:
+		if stderrs.Is(err1, target) {
+			return true
+		}
+		for _, err := range e1.Unwrap() {
+			if Is(err, target) {
+				return true
+			}
+		}
+		return false
 	}
-	return e1.Is(target)
+	return stderrs.Is(err1, target)
 }
 
+// WithErr 同 AsCode, 但找不到 *Code 时生成的 *Code 包含调用 WithErr 处的调用栈
 func WithErr(err error) (e *Code) {
-	e, ok := err.(*Code)
-	if ok {
+	if e = findCode(err); e != nil {
 		return
 	}
 	return NewCode(1, DefaultCode, err.Error())
 }
 
+// AsCode 沿 err 的 Unwrap 链(包括 Join 等多个分支, 深度优先)查找最外层的 *Code, 原样返回以保留调用栈;
+// 外层有 WrapCode 附加的错误码时, 返回替换了错误码的副本; 找不到时返回 DefaultCode 和 err.Error()
 func AsCode(err error) (e *Code) {
-	e, ok := err.(*Code)
-	if ok {
+	if e = findCode(err); e != nil {
 		return
 	}
 	return &Code{
@@ -36,3 +50,42 @@ func AsCode(err error) (e *Code) {
 		msg:  err.Error(),
 	}
 }
+
+// FindCode 同 AsCode, 但找不到 *Code 时返回 nil, false, 用于区分业务错误和其它 error
+func FindCode(err error) (e *Code, ok bool) {
+	e = findCode(err)
+	return e, e != nil
+}
+
+// findCode 查找 err 链上最外层的 *Code; 只有 WrapCode 附加的错误码时返回该错误码
+func findCode(err error) *Code {
+	var outer *Code // 最外层 WrapCode 附加的错误码
+	for err != nil {
+		switch e := err.(type) {
+		case *Code:
+			return withOuterCode(e, outer)
+		case *wrapper:
+			if outer == nil {
+				outer = e.code
+			}
+		case multiError:
+			for _, err := range e.Unwrap() {
+				if c := findCode(err); c != nil {
+					return withOuterCode(c, outer)
+				}
+			}
+			return outer
+		}
+		err = stderrs.Unwrap(err)
+	}
+	return outer
+}
+
+func withOuterCode(c, outer *Code) *Code {
+	if outer == nil || outer.code == c.code {
+		return c
+	}
+	c1 := *c
+	c1.code = outer.code
+	return &c1
+}
@@ -31,6 +31,43 @@ func TestStd(t *testing.T) {
 		assert.True(t, Is(err4, err))
 		err5 := Wrap(err, errTrace)
 		assert.True(t, Is(err5, err))
+		err6 := Join(err2, fmt.Errorf(errTrace+":%w", err5))
+		assert.True(t, Is(err6, err))
+		assert.False(t, Is(err6, NewErr(errCode+1, errMsg)))
+	})
+	t.Run("AsCode", func(t *testing.T) {
+		c := NewCode(0, errCode, errMsg)
+		for _, err := range []error{
+			c,
+			Wrap(c, errTrace),
+			fmt.Errorf(errTrace+":%w", Wrap(c, errTrace)),
+			Join(stderrs.New(errMsg), fmt.Errorf(errTrace+":%w", c)),
+			fmt.Errorf("%w, %w", stderrs.New(errMsg), Wrap(c, errTrace)),
+		} {
+			assert.Equal(t, c, AsCode(err))
+			assert.Equal(t, c, WithErr(err))
+		}
+
+		// 取最外层的 *Code
+		c1 := NewCode(0, errCode+1, errMsg).WithErr(Wrap(c, errTrace))
+		assert.Equal(t, c1, AsCode(Wrap(c1, errTrace)))
+
+		// WrapCode 附加的错误码优先, 调用栈和 msg 保持不变
+		e := AsCode(WrapCode(Wrap(c, errTrace), NewCode(-1, 2002, ""), errTrace))
+		assert.Equal(t, 2002, e.Code())
+		assert.Equal(t, c.Msg(), e.Msg())
+		assert.Equal(t, c.Stack(), e.Stack())
+		assert.Equal(t, errCode, c.Code())
+		assert.Equal(t, 2002, AsCode(WrapCode(stderrs.New(errMsg), NewCode(-1, 2002, ""), errTrace)).Code())
+
+		err := fmt.Errorf(errTrace+":%w", stderrs.New(errMsg))
+		e = AsCode(err)
+		assert.Equal(t, DefaultCode, e.Code())
+		assert.Equal(t, err.Error(), e.Msg())
+		assert.Nil(t, e.Stack())
+		e = WithErr(err)
+		assert.Equal(t, DefaultCode, e.Code())
+		assert.True(t, len(e.Stack()) > 0)
 	})
 }
 
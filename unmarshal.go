@@ -0,0 +1,180 @@
+// MIT License
+//
+// Copyright (c) 2021 Xiantu Li
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type jsonEnvelope struct {
+	Cause   json.RawMessage   `json:"cause"`
+	Wrapper []json.RawMessage `json:"wrapper"`
+}
+
+// jsonLayer 是 cause 或 wrapper 数组中的一个对象, 可能是 Code、wrapper 或 Join
+type jsonLayer struct {
+	Code   *int              `json:"code"`
+	Msg    string            `json:"msg"`
+	Stack  []string          `json:"stack"`
+	Trace  *string           `json:"trace"`
+	Caller string            `json:"caller"`
+	Errors []json.RawMessage `json:"errors"`
+	Fields jsonFields        `json:"fields"`
+}
+
+// jsonFields 按原顺序解析 fields 对象
+type jsonFields []Field
+
+func (fs *jsonFields) UnmarshalJSON(bs []byte) (err error) {
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return fmt.Errorf("fields: expect object, got %s", bs)
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		f := Field{Key: t.(string)}
+		if err = dec.Decode(&f.Value); err != nil {
+			return err
+		}
+		if n, ok := f.Value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				f.Value = i
+			} else if v, err := n.Float64(); err == nil {
+				f.Value = v
+			}
+		}
+		*fs = append(*fs, f)
+	}
+	return
+}
+
+// ParseJSON 是 MarshalJSON 和 MarshalJSON2 的逆过程: 还原出 *Code、wrapper 和 Join,
+// wrapper 保留序列化时记录的 caller, 因此还原后的 err 仍可用于 Is、Code() 和各种格式化输出;
+// 序列化前不是本库类型的 error 会被还原为只包含文本的 error。
+// 返回值按 Go 的惯例排列: parsed 为还原出的 error (bs 为 null 时为 nil), err 为解析失败的原因
+func ParseJSON(bs []byte) (parsed error, err error) {
+	bs = bytes.TrimSpace(bs)
+	if bytes.Equal(bs, []byte(`null`)) {
+		return nil, nil
+	}
+	env := jsonEnvelope{}
+	if err = json.Unmarshal(bs, &env); err != nil {
+		return
+	}
+	if len(env.Cause) == 0 {
+		// Code.MarshalJSON 对不带 cause 的 Code 直接输出 Code 对象
+		return parseJSONLayer(bs, nil, true)
+	}
+	if parsed, err = parseJSONLayer(env.Cause, nil, true); err != nil {
+		return nil, err
+	}
+	for _, raw := range env.Wrapper {
+		if parsed, err = parseJSONLayer(raw, parsed, false); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// parseJSONLayer 解析一层, inner 为已还原的内层 error
+func parseJSONLayer(raw json.RawMessage, inner error, isCause bool) (parsed error, err error) {
+	if raw[0] == '"' {
+		var msg string
+		if err = json.Unmarshal(raw, &msg); err != nil {
+			return
+		}
+		return errors.New(msg), nil
+	}
+	l := jsonLayer{}
+	if err = json.Unmarshal(raw, &l); err != nil {
+		return
+	}
+	switch {
+	case l.Trace != nil:
+		w := &wrapper{
+			err:    inner,
+			msg:    *l.Trace,
+			fields: l.Fields,
+			parsed: newFrame(l.Caller),
+		}
+		if len(l.Stack) > 0 {
+			w.cache = newCallers(l.Stack)
+		}
+		if l.Code != nil {
+			w.code = &Code{code: *l.Code}
+			if info, ok := Lookup(*l.Code); ok {
+				w.code.msg = info.Msg
+			}
+		}
+		return w, nil
+	case l.Code != nil:
+		c := &Code{
+			code:   *l.Code,
+			msg:    l.Msg,
+			cache:  newCallers(l.Stack),
+			err:    inner,
+			fields: l.Fields,
+		}
+		return c, nil
+	case isCause && l.Errors != nil:
+		j := &joinError{
+			errs:  make([]error, 0, len(l.Errors)),
+			cache: newCallers(l.Stack),
+		}
+		for _, raw := range l.Errors {
+			branch, err := ParseJSON(raw)
+			if err != nil {
+				return nil, err
+			}
+			if branch == nil {
+				// Join 会忽略 nil, 序列化结果中不会有 null 分支
+				return nil, fmt.Errorf("null error in join: %s", raw)
+			}
+			j.errs = append(j.errs, branch)
+		}
+		return j, nil
+	}
+	return nil, fmt.Errorf("unknown error layer: %s", raw)
+}
+
+// UnmarshalJSON json.Unmarshaler 的方法, 接受 Code.MarshalJSON 和 MarshalJSON 的输出;
+// 若最外层不是 *Code, 则取链上最外层的 *Code, 其外的 wrapper 会被丢弃
+func (e *Code) UnmarshalJSON(bs []byte) error {
+	parsed, err := ParseJSON(bs)
+	if err != nil {
+		return err
+	}
+	var c *Code
+	if !errors.As(parsed, &c) {
+		return fmt.Errorf("no error code in json: %s", bs)
+	}
+	*e = *c
+	return nil
+}
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrs "errors"
+	"testing"
+
+	pkgerrs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJSON(t *testing.T) {
+	code := &Code{code: errCode, msg: errMsg, cache: newCallers([]string{"(file1:88) func1", `(a"b.go:1) f`})}
+	wrap := func(err error) error {
+		w := Wrap(err, errTrace+"\n\"x\"").(*wrapper)
+		w.pc = testFrame
+		return w
+	}
+	cases := map[string]error{
+		"nil":         nil,
+		"code":        code,
+		"code.new":    NewCode(0, errCode, errMsg),
+		"code.fields": code.With("user_id", 1, "name", "a b", "ok", true, "rate", 0.5, "list", []interface{}{"x"}),
+		"code.wrap":   wrap(wrap(code)),
+		"code.cause":  wrap(NewCode(0, errCode, errMsg).WithErr(wrap(stderrs.New(errMsg)))),
+		"std":         stderrs.New(errMsg),
+		"std.wrap":    With(wrap(stderrs.New(errMsg)), "order_id", 2),
+		"pkg.wrap":    wrap(pkgerrs.New(errMsg)),
+		"newline":     wrap(NewLine(errMsg)),
+		"join":        wrap(Join(code, wrap(stderrs.New(errMsg)), stderrs.Join(stderrs.New("a"), code))),
+	}
+	for name, err := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, f := range []func(error) []byte{MarshalJSON, MarshalJSON2} {
+				bs := f(err)
+				assert.True(t, json.Valid(bs), string(bs))
+				got, errParse := ParseJSON(bs)
+				assert.Nil(t, errParse)
+				assert.Equal(t, string(bs), string(f(got)))
+				assert.Equal(t, string(MarshalJSON(err)), string(MarshalJSON(got)))
+				if err != nil {
+					assert.Equal(t, string(MarshalText(err)), string(MarshalText(got)))
+				}
+			}
+		})
+	}
+
+	t.Run("Is", func(t *testing.T) {
+		c1 := code.WithErr(stderrs.New(errMsg))
+		err := wrap(c1)
+		got, errParse := ParseJSON(MarshalJSON(err))
+		assert.Nil(t, errParse)
+		assert.True(t, Is(got, NewCode(0, errCode, "")))
+		var c *Code
+		assert.True(t, stderrs.As(got, &c))
+		assert.Equal(t, errCode, c.Code())
+		assert.Equal(t, c1.Stack(), c.Stack())
+	})
+
+	t.Run("Code.UnmarshalJSON", func(t *testing.T) {
+		bs, err := json.Marshal(code)
+		assert.Nil(t, err)
+		c := &Code{}
+		assert.Nil(t, json.Unmarshal(bs, c))
+		assert.Equal(t, code.Error(), c.Error())
+
+		c = &Code{}
+		assert.Nil(t, json.Unmarshal(MarshalJSON(wrap(code)), c))
+		assert.Equal(t, code.Error(), c.Error())
+
+		assert.NotNil(t, json.Unmarshal(MarshalJSON(stderrs.New(errMsg)), c))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, str := range []string{`{`, `{"cause":1}`, `{"cause":{"x":1}}`, `{"cause":"a","wrapper":[{"x":1}]}`,
+			`{"cause":{"errors":[null]}}`, `{"errors":[{"code":1,"msg":"a"},null]}`} {
+			parsed, err := ParseJSON([]byte(str))
+			assert.NotNil(t, err, str)
+			assert.Nil(t, parsed, str)
+		}
+	})
+}
@@ -23,23 +23,106 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"runtime"
-	"sync/atomic"
-	"unsafe"
-)
-
-var (
-	mFramesCache unsafe.Pointer = func() unsafe.Pointer {
-		m := make(map[uintptr]*frame)
-		return unsafe.Pointer(&m)
-	}()
+	"strconv"
+	"strings"
 )
 
 type wrapper struct {
 	pc  [1]uintptr
 	err error
 	msg string
+
+	fields []Field
+	parsed *frame // 预先解析好的 caller, 如 ParseJSON 还原的 wrapper; 为 nil 时按 pc 解析
+
+	cache *callers // WrapStack 记录的完整调用栈, Wrap 生成的为 nil
+	skip  int
+
+	code *Code // WrapCode 附加的业务错误码
+}
+
+// WrapCode 同 Wrap, 但给这一层附加业务错误码 code, 用于在层边界把底层错误转换为业务错误, 如:
+//
+//	errors.WrapCode(err, ErrOrderNotFound, "load %d", id)
+//
+// CodeOf、Is 以最外层的错误码为准; code 为 nil 时等同于 Wrap
+//
+//go:noinline
+func WrapCode(err error, code *Code, format string, ifaces ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	if len(ifaces) > 0 {
+		format = fmt.Sprintf(format, ifaces...)
+	}
+	return &wrapper{
+		pc:   getPC(),
+		err:  err,
+		msg:  format,
+		code: code,
+	}
+}
+
+// Is WrapCode 附加的错误码与 target 相同时返回 true
+func (e *wrapper) Is(target error) bool {
+	return e.code != nil && e.code.Is(target)
+}
+
+// CodeOf 沿 err 的 Unwrap 链查找, 返回最外层的错误码(*Code 或 WrapCode 附加的); 都没有时返回 DefaultCode;
+// 查找规则与 AsCode 相同
+func CodeOf(err error) int {
+	if c := findCode(err); c != nil {
+		return c.code
+	}
+	return DefaultCode
+}
+
+// WrapStack 同 Wrap, 但像 NewCode 一样记录完整的调用栈(受 SetStackDepth、SetPathMode、SetFrameFilter 影响);
+// 输出时会去掉与 cause 调用栈末尾相同的部分. 适合在被很多地方调用的公共函数里使用
+//
+//go:noinline
+func WrapStack(err error, format string, ifaces ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	if len(ifaces) > 0 {
+		format = fmt.Sprintf(format, ifaces...)
+	}
+	c := newStackCode(1)
+	return &wrapper{
+		pc:    getPC(),
+		err:   err,
+		msg:   format,
+		cache: c.cache,
+		skip:  c.skip,
+	}
+}
+
+// Stack 返回 WrapStack 记录的完整调用栈, Wrap 生成的 wrapper 返回 nil
+func (e *wrapper) Stack() (stack []string) {
+	if e.cache == nil {
+		return
+	}
+	if len(e.cache.stack) > e.skip {
+		return e.cache.stack[e.skip:]
+	}
+	return
+}
+
+// innerStack 返回 err 链上最近的一个调用栈, 用于去掉 WrapStack 调用栈中与之重复的部分
+func innerStack(err error) []string {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if s, ok := err.(interface{ Stack() []string }); ok {
+			if stack := s.Stack(); len(stack) > 0 {
+				return stack
+			}
+		}
+	}
+	return nil
 }
 
 func WrapSlow(err error, format string, ifaces ...interface{}) error {
@@ -74,12 +157,35 @@ func (e *wrapper) Unwrap() error {
 }
 
 func (e *wrapper) Error() string {
+	if mode := GetErrorMode(); mode == ErrorMsg || mode == ErrorCaller {
+		return string(e.appendError(nil, mode))
+	}
 	cache := e.fmt()
 	buf := NewWriteBuffer(cache.textSize())
 	cache.text(buf)
 	return buf.String()
 }
 
+// appendError 按 ErrorMsg 或 ErrorCaller 模式追加 "[code, ]trace[ caller]: cause"
+func (e *wrapper) appendError(bs []byte, mode ErrorMode) []byte {
+	if e.code != nil {
+		bs = strconv.AppendInt(bs, int64(e.code.code), 10)
+		bs = append(bs, ", "...)
+	}
+	bs = append(bs, e.msg...)
+	if mode == ErrorCaller {
+		bs = append(bs, ' ')
+		bs = append(bs, e.parse().stack...)
+	}
+	if e.err != nil {
+		if len(bs) > 0 {
+			bs = append(bs, ": "...)
+		}
+		bs = append(bs, causeText(e.err)...)
+	}
+	return bs
+}
+
 func (e *wrapper) MarshalJSON() ([]byte, error) {
 	return MarshalJSON(e), nil
 }
@@ -87,8 +193,12 @@ func (e *wrapper) MarshalJSON() ([]byte, error) {
 func (e *wrapper) Format(s fmt.State, verb rune) {
 	switch verb {
 	case 'v':
+		if s.Flag('#') {
+			io.WriteString(s, e.GoString())
+			return
+		}
 		if s.Flag('+') {
-			s.Write(MarshalText(e))
+			WriteText(s, e)
 			return
 		}
 		fallthrough
@@ -100,97 +210,178 @@ func (e *wrapper) Format(s fmt.State, verb rune) {
 	}
 }
 
-func (e *wrapper) parse() (f *frame) {
-	mFC := *(*map[uintptr]*frame)(atomic.LoadPointer(&mFramesCache))
-	f, ok := mFC[e.pc[0]]
-	if !ok {
-		f = &frame{}
-		// file, n := runtime.FuncForPC(e.pc).FileLine(e.pc)
-		cf, _ := runtime.CallersFrames(e.pc[:]).Next()
-		f.stack = toCaller(cf).String()
-		l, yes := countEscape(f.stack)
-		f.attr = uint64(l) << 32
-		if yes {
-			f.attr |= 1
-		}
-
-		mFC2 := make(map[uintptr]*frame, len(mFC)+10)
-		mFC2[e.pc[0]] = f
-		for {
-			p := atomic.LoadPointer(&mFramesCache)
-			mFC = *(*map[uintptr]*frame)(p)
-			for k, v := range mFC {
-				mFC2[k] = v
-			}
-			swapped := atomic.CompareAndSwapPointer(&mFramesCache, p, unsafe.Pointer(&mFC2))
-			if swapped {
-				break
-			}
-		}
+// GoString fmt.GoStringer 的方法, 用于 %#v
+func (e *wrapper) GoString() string {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "&errors.wrapper{msg:%q", e.msg)
+	if e.code != nil {
+		fmt.Fprintf(buf, ", code:%d", e.code.code)
 	}
-	return f
+	if stack := e.Stack(); len(stack) > 0 {
+		fmt.Fprintf(buf, ", stack:%#v", stack)
+	} else if e.cache == nil {
+		fmt.Fprintf(buf, ", caller:%q", e.parse().stack)
+	}
+	if len(e.fields) > 0 {
+		fmt.Fprintf(buf, ", fields:%#v", e.fields)
+	}
+	if e.err != nil {
+		fmt.Fprintf(buf, ", err:%#v", e.err)
+	}
+	buf.WriteByte('}')
+	return buf.String()
 }
 
-var cacheWrapper = RCUCache[[1]uintptr, *frame]{
-	New: func(k [1]uintptr) (v *frame) {
-		f := &frame{}
-		cf, _ := runtime.CallersFrames(k[:]).Next()
-		f.stack = toCaller(cf).String()
-		l, yes := countEscape(f.stack)
-		f.attr = uint64(l) << 32
-		if yes {
-			f.attr |= 1
-		}
-		return f
-	},
+func (e *wrapper) parse() (f *frame) {
+	if e.parsed != nil {
+		return e.parsed
+	}
+	return cacheWrapper.Get([2]uintptr{e.pc[0], uintptr(GetPathMode())})
 }
 
-func (e *wrapper) parse2() (f *frame) {
-	return cacheWrapper.Get(e.pc)
+// cacheWrapper 缓存 wrapper 的 caller, key 为 pc 和路径格式, 不同路径格式的结果同时保留; 受 SetCacheLimit 限制
+var cacheWrapper = RCUCache[[2]uintptr, *frame]{
+	New: func(k [2]uintptr) (v *frame) {
+		cf, _ := runtime.CallersFrames(k[:1]).Next()
+		return newFrame(toCallerMode(cf, PathMode(k[1])).String())
+	},
 }
 
-func (e *wrapper) fmt() fmtWrapper {
-	return fmtWrapper{trace: e.msg, frame: e.parse()}
+func (e *wrapper) fmt() (f fmtWrapper) {
+	f = fmtWrapper{trace: e.msg, frame: e.parse(), fields: e.fields}
+	if e.code != nil {
+		f.code = strconv.Itoa(e.code.code)
+	}
+	if stack := e.Stack(); len(stack) > 0 {
+		// 去掉与 cause 调用栈末尾相同的 frame, 至少保留一个
+		inner := innerStack(e.err)
+		i, j := len(stack), len(inner)
+		for i > 1 && j > 0 && stack[i-1] == inner[j-1] {
+			i--
+			j--
+		}
+		f.callers, f.from, f.to = e.cache, e.skip, e.skip+i
+	}
+	return
 }
 
 type frame struct {
 	stack string
 	attr  uint64 // count:escape ==> uint32:uint32
 }
+
+func newFrame(stack string) *frame {
+	f := &frame{stack: stack}
+	l, yes := countEscape(stack)
+	f.attr = uint64(l) << 32
+	if yes {
+		f.attr |= 1
+	}
+	return f
+}
 type fmtWrapper struct {
 	trace       string
 	traceEscape bool
 	*frame
+
+	fields    []Field
+	fieldsBuf []byte // jsonSize 或 textSize 时预先序列化的 fields
+
+	callers  *callers // WrapStack 的调用栈, 只输出 callers.stack[from:to]
+	from, to int
+
+	code string // WrapCode 附加的错误码, 没有时为空
 }
 
-func (f *fmtWrapper) jsonSize() (l int) {
-	l, f.traceEscape = countEscape(f.trace)
-	l += len(`{"trace":"","caller":""}`) + (int(f.attr) >> 32)
+func (f *fmtWrapper) jsonSize(s *jsonSchema) (l int) {
+	l = len(`{""}`) + len(s.caller) + (int(f.attr) >> 32)
+	if f.code != "" {
+		l += len(s.code) + len(f.code) + len(`,`)
+	}
+	if s.keep(f.trace) {
+		n, escape := countEscape(f.trace)
+		l += len(s.trace) + n + len(`"",`)
+		f.traceEscape = escape
+	}
+	if len(f.fields) > 0 {
+		f.fieldsBuf = appendFieldsJSON(f.fieldsBuf[:0], s.fields, f.fields)
+		l += len(f.fieldsBuf)
+	}
+	if f.callers != nil {
+		n := 0
+		for _, str := range f.callers.stack[f.from:f.to] {
+			c, _ := countEscape(str)
+			n += c
+		}
+		l += s.stackSize(f.to-f.from, n)
+	}
 	return
 }
 
-func (f *fmtWrapper) textSize() int {
-	return len(",\n    ;") + len(f.trace) + len(f.stack)
+func (f *fmtWrapper) textSize() (l int) {
+	if len(f.fields) > 0 {
+		f.fieldsBuf = appendFieldsText(f.fieldsBuf[:0], f.fields)
+	}
+	if f.code != "" {
+		l += len(f.code) + len(", ")
+	}
+	if f.callers != nil {
+		l += len(f.trace) + len(f.fieldsBuf) + len(";")
+		for _, str := range f.callers.stack[f.from:f.to] {
+			l += len(str) + len(",\n    ")
+		}
+		return
+	}
+	return l + len(",\n    ;") + len(f.trace) + len(f.stack) + len(f.fieldsBuf)
 }
 
-func (f *fmtWrapper) json(buf *writeBuffer) {
-	buf.WriteString(`{"trace":"`)
-	if !f.traceEscape {
-		buf.WriteString(f.trace)
-	} else {
-		buf.WriteEscape(f.trace)
+func (f *fmtWrapper) json(buf *writeBuffer, s *jsonSchema) {
+	buf.WriteByte('{')
+	if f.code != "" {
+		buf.WriteString(s.code)
+		buf.WriteString(f.code)
+		buf.WriteByte(',')
+	}
+	if s.keep(f.trace) {
+		buf.WriteString(s.trace)
+		buf.WriteByte('"')
+		if !f.traceEscape {
+			buf.WriteString(f.trace)
+		} else {
+			buf.WriteEscape(f.trace)
+		}
+		buf.WriteString(`",`)
 	}
-	buf.WriteString(`","caller":"`)
+	buf.WriteString(s.caller)
+	buf.WriteByte('"')
 	if (f.attr & 1) == 0 {
 		buf.WriteString(f.stack)
 	} else {
 		buf.WriteEscape(f.stack)
 	}
-	buf.WriteString(`"}`)
+	buf.WriteByte('"')
+	if f.callers != nil {
+		buf.buf = s.appendStack(buf.buf, f.callers, f.from, f.to)
+	}
+	buf.Write(f.fieldsBuf)
+	buf.WriteByte('}')
 }
 
 func (f *fmtWrapper) text(buf *writeBuffer) {
+	if f.code != "" {
+		buf.WriteString(f.code)
+		buf.WriteString(", ")
+	}
 	buf.WriteString(f.trace)
+	buf.Write(f.fieldsBuf)
+	if f.callers != nil {
+		for _, str := range f.callers.stack[f.from:f.to] {
+			buf.WriteString(",\n    ")
+			buf.WriteString(str)
+		}
+		buf.WriteByte(';')
+		return
+	}
 	buf.WriteString(",\n    ")
 	buf.WriteString(f.stack)
 	buf.WriteByte(';')
@@ -6,7 +6,6 @@ import (
 	stderrs "errors"
 	"fmt"
 	"runtime"
-	"sync/atomic"
 	"testing"
 
 	pkgerrs "github.com/pkg/errors"
@@ -61,12 +60,46 @@ func Test_wrapper(t *testing.T) {
 	})
 	t.Run("wrapper.parse.cache", func(t *testing.T) {
 		e := Wrap(err, errTrace).(*wrapper)
-		mFrames := *(*map[uintptr]*frame)(atomic.LoadPointer(&mFramesCache))
-		delete(mFrames, e.pc[0])
 		caller := e.parse()
 		cacheCaller := e.parse()
 		assert.Equal(t, caller, cacheCaller)
 	})
+	t.Run("wrapper.parse.limit", func(t *testing.T) {
+		old := cacheWrapper.cache
+		defer func() {
+			cacheWrapper.SetLimit(0, false)
+			cacheWrapper.cache = old
+		}()
+		SetCacheLimit(8, true)
+		defer SetCacheLimit(0, false)
+		defer SetPathMode(PathDefault)
+
+		e := Wrap(err, errTrace).(*wrapper)
+		for i := 1; i <= 32; i++ {
+			SetPathMode(PathLast(i))
+			assert.NotEmpty(t, e.parse().stack)
+			assert.LessOrEqual(t, cacheWrapper.Len(), 8)
+		}
+	})
+	t.Run("wrapper.parse.mode", func(t *testing.T) {
+		defer SetPathMode(PathDefault)
+		e := Wrap(err, errTrace).(*wrapper)
+		SetPathMode(PathFull)
+		full := e.parse()
+		SetPathMode(PathModule)
+		module := e.parse()
+		assert.NotEqual(t, full.stack, module.stack)
+
+		// 交替使用两种路径格式时不会重新解析
+		misses := cacheWrapper.counter.misses.Load()
+		for i := 0; i < 4; i++ {
+			SetPathMode(PathFull)
+			assert.Same(t, full, e.parse())
+			SetPathMode(PathModule)
+			assert.Same(t, module, e.parse())
+		}
+		assert.Equal(t, misses, cacheWrapper.counter.misses.Load())
+	})
 	t.Run("wrapper.parse", func(t *testing.T) {
 		e := Wrap(err, errTrace).(*wrapper)
 		e.pc = testFrame
@@ -113,6 +146,106 @@ func Test_wrapper(t *testing.T) {
 	})
 }
 
+func sharedHelper(err error) error {
+	return WrapStack(err, errTrace)
+}
+
+func Test_WrapStack(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		assert.Nil(t, WrapStack(nil, errTrace))
+	})
+	t.Run("Stack", func(t *testing.T) {
+		var e1, e2 *wrapper
+		deepCall(2, func() {
+			e1 = sharedHelper(stderrs.New(errMsg)).(*wrapper)
+		})
+		e2 = sharedHelper(stderrs.New(errMsg)).(*wrapper)
+		assert.Equal(t, e1.Stack()[0], e2.Stack()[0])
+		assert.Equal(t, e1.parse().stack, e1.Stack()[0])
+		assert.NotEqual(t, e1.Stack()[1], e2.Stack()[1])
+		assert.Equal(t, len(e1.Stack()), len(e1.StackPCs()))
+		assert.Nil(t, Wrap(e1, errTrace).(*wrapper).Stack())
+	})
+	t.Run("dedup", func(t *testing.T) {
+		var c *Code
+		var err error
+		deepCall(1, func() {
+			c = NewCode(0, errCode, errMsg)
+			err = sharedHelper(c)
+		})
+		stack := err.(*wrapper).Stack()
+
+		m := struct {
+			Wrapper []struct {
+				Caller string   `json:"caller"`
+				Stack  []string `json:"stack"`
+			} `json:"wrapper"`
+		}{}
+		assert.Nil(t, json.Unmarshal(MarshalJSON(err), &m))
+		// 只剩 sharedHelper 和调用 sharedHelper 的闭包, 其余与 c 的调用栈相同
+		assert.Equal(t, stack[:2], m.Wrapper[0].Stack)
+		assert.Equal(t, stack[0], m.Wrapper[0].Caller)
+		assert.Equal(t, MarshalJSON(err), MarshalJSON2(err))
+
+		str := errTrace + ",\n    " + stack[0] + ",\n    " + stack[1] + ";"
+		assert.Equal(t, str, err.(*wrapper).Error())
+		assert.Equal(t, string(MarshalText(c))+"\n"+str, string(MarshalText(err)))
+
+		e, errParse := ParseJSON(MarshalJSON(err))
+		assert.Nil(t, errParse)
+		assert.Equal(t, MarshalJSON(err), MarshalJSON(e))
+	})
+}
+
+func Test_WrapCode(t *testing.T) {
+	errNotFound := NewCode(-1, 2002, "not found")
+	errDenied := NewCode(-1, 2003, "denied")
+	c := NewCode(0, 1001, "db")
+
+	t.Run("nil", func(t *testing.T) {
+		assert.Nil(t, WrapCode(nil, errNotFound, errTrace))
+	})
+	t.Run("CodeOf", func(t *testing.T) {
+		err := WrapCode(c, errNotFound, "load %d", 7)
+		assert.Equal(t, 2002, CodeOf(err))
+		assert.Equal(t, 2002, CodeOf(Wrap(err, errTrace)))
+		assert.Equal(t, 2003, CodeOf(WrapCode(err, errDenied, errTrace)))
+		assert.Equal(t, 1001, CodeOf(WrapCode(c, nil, errTrace)))
+		assert.Equal(t, 1001, CodeOf(fmt.Errorf("x: %w", c)))
+		assert.Equal(t, 2002, CodeOf(fmt.Errorf("x: %w", err)))
+		assert.Equal(t, 2002, CodeOf(Join(stderrs.New(errMsg), err)))
+		assert.Equal(t, DefaultCode, CodeOf(stderrs.New(errMsg)))
+		assert.Equal(t, DefaultCode, CodeOf(nil))
+
+		// 与 AsCode 使用同一套查找规则
+		for _, err := range []error{err, WrapCode(Join(stderrs.New(errMsg), err), errDenied, errTrace),
+			Join(WrapCode(stderrs.New(errMsg), errDenied, errTrace), c), stderrs.Join(fmt.Errorf("x: %w", c))} {
+			assert.Equal(t, AsCode(err).Code(), CodeOf(err))
+		}
+	})
+	t.Run("Is", func(t *testing.T) {
+		err := WrapCode(c, errNotFound, errTrace)
+		assert.True(t, Is(err, errNotFound))
+		assert.True(t, stderrs.Is(err, errNotFound))
+		assert.True(t, Is(err, c))
+		assert.False(t, Is(err, errDenied))
+	})
+	t.Run("format", func(t *testing.T) {
+		err := WrapCode(stderrs.New(errMsg), errNotFound, "load %d", 7)
+		err.(*wrapper).pc = testFrame
+		str := `{"cause":"msg!","wrapper":[{"code":2002,"trace":"load 7","caller":"(file1:88) func1"}]}`
+		assert.Equal(t, str, string(MarshalJSON(err)))
+		assert.Equal(t, str, string(MarshalJSON2(err)))
+		assert.Equal(t, "2002, load 7,\n    (file1:88) func1;", err.Error())
+		assert.Equal(t, "msg!;\n2002, load 7,\n    (file1:88) func1;", string(MarshalText(err)))
+
+		e, errParse := ParseJSON(MarshalJSON(err))
+		assert.Nil(t, errParse)
+		assert.Equal(t, 2002, CodeOf(e))
+		assert.Equal(t, str, string(MarshalJSON(e)))
+	})
+}
+
 func BenchmarkWrap(b *testing.B) {
 	runs := []struct {
 		funcName string                //函数名字
@@ -171,12 +304,6 @@ func BenchmarkWrapperParse(b *testing.B) {
 			w.parse()
 		}
 	})
-	b.Run("parse2", func(b *testing.B) {
-		b.ReportAllocs()
-		for i := 0; i < b.N; i++ {
-			w.parse2()
-		}
-	})
 }
 
 func BenchmarkWrapperMarshal(b *testing.B) {
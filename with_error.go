@@ -23,6 +23,7 @@
 package errors
 
 import (
+	"fmt"
 	"reflect"
 	"sync"
 )
@@ -38,6 +39,9 @@ var (
 	}()
 )
 
+// Register 注册 e 的类型作为 cause 输出时的文本, 如隐藏其中的敏感信息;
+// Error()、MarshalText、MarshalJSON 等输出 WithErr、Wrap 的 cause 时都会使用它。
+// 本包的 *Code、wrapper、Join 按各自的格式输出, 不受注册的函数影响
 func Register(e error, f func(err error) string) (err error) {
 	if _, loaded := mErrFunc.LoadOrStore(errKey(e), f); loaded {
 		err = NewCode(1, 0, "error type already registered")
@@ -59,3 +63,23 @@ func getErrorFunc(err error) (f func(err error) string) {
 	f = v.(func(err error) string)
 	return
 }
+
+// causeText 返回 err 作为 cause 输出时的文本: 非本包的 error 优先使用 Register 注册的函数, 否则为 Error()
+func causeText(err error) string {
+	switch err.(type) {
+	case *Code, *wrapper, *joinError:
+	default:
+		if f := getErrorFunc(err); f != nil {
+			return f(err)
+		}
+	}
+	return err.Error()
+}
+
+// formatterText 同 causeText, 但没有注册的函数时使用 %+v, 以保留 pkg/errors 等记录的调用栈
+func formatterText(err error) string {
+	if f := getErrorFunc(err); f != nil {
+		return f(err)
+	}
+	return fmt.Sprintf("%+v", err)
+}
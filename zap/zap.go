@@ -46,6 +46,44 @@ func (log *Logger) getZapCore() zapcore.Core {
 	return *(*zapcore.Core)(unsafe.Pointer(log))
 }
 
+// pathCore 记录 Logger 输出 caller 时的路径格式, 见 WithPathMode
+type pathCore struct {
+	zapcore.Core
+	mode errors.PathMode
+}
+
+func (c *pathCore) With(fields []zapcore.Field) zapcore.Core {
+	return &pathCore{Core: c.Core.With(fields), mode: c.mode}
+}
+
+func (c *pathCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.Core.Check(ent, ce)
+}
+
+// WithPathMode 返回设置 Logger 输出 caller 时文件路径格式的 zap.Option, 可用于 New 或 WithOptions;
+// With、Named、Sugar 派生的 logger 沿用该设置; 默认 errors.PathDefault 表示跟随 errors.SetPathMode;
+// 需要放在其它 zap.WrapCore 之后
+func WithPathMode(mode errors.PathMode) zap.Option {
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		if pc, ok := c.(*pathCore); ok {
+			c = pc.Core
+		}
+		return &pathCore{Core: c, mode: mode}
+	})
+}
+
+func (log *Logger) pathMode() errors.PathMode {
+	if c, ok := log.getZapCore().(*pathCore); ok {
+		return c.mode
+	}
+	return errors.PathDefault
+}
+
+// WithOptions 同 zap.Logger.WithOptions, 如 WithOptions(WithPathMode(errors.PathFull))
+func (log *Logger) WithOptions(opts ...zap.Option) *Logger {
+	return toLogger(log.Logger.WithOptions(opts...))
+}
+
 func New(core zapcore.Core, options ...zap.Option) *Logger {
 	// options = append(options, zap.WithCaller(false))
 	logger := zap.New(core, options...)
@@ -56,7 +94,7 @@ func (log *Logger) Log(lvl zapcore.Level, msg string, fields ...zap.Field) {
 	if !log.getZapCore().Enabled(lvl) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(log.pathMode())
 	fields = append(fields, zap.String("caller", c.FileLine))
 	log.Logger.Log(lvl, msg, fields...)
 }
@@ -65,7 +103,7 @@ func (log *Logger) Debug(msg string, fields ...zap.Field) {
 	if !log.getZapCore().Enabled(zap.DebugLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(log.pathMode())
 	fields = append(fields, zap.String("caller", c.FileLine))
 	log.Logger.Debug(msg, fields...)
 }
@@ -74,7 +112,7 @@ func (log *Logger) Info(msg string, fields ...zap.Field) {
 	if !log.getZapCore().Enabled(zap.InfoLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(log.pathMode())
 	fields = append(fields, zap.String("caller", c.FileLine))
 	log.Logger.Info(msg, fields...)
 }
@@ -83,7 +121,7 @@ func (log *Logger) Warn(msg string, fields ...zap.Field) {
 	if !log.getZapCore().Enabled(zap.WarnLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(log.pathMode())
 	fields = append(fields, zap.String("caller", c.FileLine))
 	log.Logger.Warn(msg, fields...)
 }
@@ -92,7 +130,7 @@ func (log *Logger) Error(msg string, fields ...zap.Field) {
 	if !log.getZapCore().Enabled(zap.ErrorLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(log.pathMode())
 	fields = append(fields, zap.String("caller", c.FileLine))
 	log.Logger.Error(msg, fields...)
 }
@@ -101,7 +139,7 @@ func (log *Logger) DPanic(msg string, fields ...zap.Field) {
 	if !log.getZapCore().Enabled(zap.DPanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(log.pathMode())
 	fields = append(fields, zap.String("caller", c.FileLine))
 	log.Logger.DPanic(msg, fields...)
 }
@@ -110,7 +148,7 @@ func (log *Logger) Panic(msg string, fields ...zap.Field) {
 	if !log.getZapCore().Enabled(zap.PanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(log.pathMode())
 	fields = append(fields, zap.String("caller", c.FileLine))
 	log.Logger.Panic(msg, fields...)
 }
@@ -119,7 +157,7 @@ func (log *Logger) Fatal(msg string, fields ...zap.Field) {
 	if !log.getZapCore().Enabled(zap.FatalLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(log.pathMode())
 	fields = append(fields, zap.String("caller", c.FileLine))
 	log.Logger.Fatal(msg, fields...)
 }
@@ -105,7 +105,7 @@ func (s *SugaredLogger) Debug(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.DebugLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgs(args)
 	s.getLogger().Logger.Debug(msg, zap.String("caller", c.FileLine))
 }
@@ -116,7 +116,7 @@ func (s *SugaredLogger) Info(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.InfoLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgs(args)
 	s.getLogger().Logger.Info(msg, zap.String("caller", c.FileLine))
 }
@@ -127,7 +127,7 @@ func (s *SugaredLogger) Warn(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.WarnLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgs(args)
 	s.getLogger().Logger.Warn(msg, zap.String("caller", c.FileLine))
 }
@@ -136,7 +136,7 @@ func (s *SugaredLogger) Error(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.ErrorLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgs(args)
 	s.getLogger().Logger.Error(msg, zap.String("caller", c.FileLine))
 }
@@ -145,7 +145,7 @@ func (s *SugaredLogger) DPanic(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.DPanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgs(args)
 	s.getLogger().Logger.DPanic(msg, zap.String("caller", c.FileLine))
 }
@@ -154,7 +154,7 @@ func (s *SugaredLogger) Panic(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.PanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgs(args)
 	s.getLogger().Logger.Panic(msg, zap.String("caller", c.FileLine))
 }
@@ -163,7 +163,7 @@ func (s *SugaredLogger) Fatal(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.FatalLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgs(args)
 	s.getLogger().Logger.Fatal(msg, zap.String("caller", c.FileLine))
 }
@@ -172,7 +172,7 @@ func (s *SugaredLogger) Debugf(template string, args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.DebugLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getTemplateArgs(template, args)
 	s.getLogger().Logger.Debug(msg, zap.String("caller", c.FileLine))
 }
@@ -181,7 +181,7 @@ func (s *SugaredLogger) Infof(template string, args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.InfoLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getTemplateArgs(template, args)
 	s.getLogger().Logger.Info(msg, zap.String("caller", c.FileLine))
 }
@@ -190,7 +190,7 @@ func (s *SugaredLogger) Warnf(template string, args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.WarnLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getTemplateArgs(template, args)
 	s.getLogger().Logger.Warn(msg, zap.String("caller", c.FileLine))
 }
@@ -199,7 +199,7 @@ func (s *SugaredLogger) Errorf(template string, args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.ErrorLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getTemplateArgs(template, args)
 	s.getLogger().Logger.Error(msg, zap.String("caller", c.FileLine))
 }
@@ -208,7 +208,7 @@ func (s *SugaredLogger) DPanicf(template string, args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.DPanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getTemplateArgs(template, args)
 	s.getLogger().Logger.DPanic(msg, zap.String("caller", c.FileLine))
 }
@@ -217,7 +217,7 @@ func (s *SugaredLogger) Panicf(template string, args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.PanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getTemplateArgs(template, args)
 	s.getLogger().Logger.Panic(msg, zap.String("caller", c.FileLine))
 }
@@ -226,7 +226,7 @@ func (s *SugaredLogger) Fatalf(template string, args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.FatalLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getTemplateArgs(template, args)
 	s.getLogger().Logger.Fatal(msg, zap.String("caller", c.FileLine))
 }
@@ -235,7 +235,7 @@ func (s *SugaredLogger) Debugw(msg string, keysAndValues ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.DebugLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	keysAndValues = append(keysAndValues, zap.String("caller", c.FileLine))
 	s.SugaredLogger.Debugw(msg, keysAndValues)
 }
@@ -244,7 +244,7 @@ func (s *SugaredLogger) Infow(msg string, keysAndValues ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.InfoLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	keysAndValues = append(keysAndValues, zap.String("caller", c.FileLine))
 	s.SugaredLogger.Infow(msg, keysAndValues)
 }
@@ -253,7 +253,7 @@ func (s *SugaredLogger) Warnw(msg string, keysAndValues ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.WarnLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	keysAndValues = append(keysAndValues, zap.String("caller", c.FileLine))
 	s.SugaredLogger.Warnw(msg, keysAndValues)
 }
@@ -262,7 +262,7 @@ func (s *SugaredLogger) Errorw(msg string, keysAndValues ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.ErrorLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	keysAndValues = append(keysAndValues, zap.String("caller", c.FileLine))
 	s.SugaredLogger.Errorw(msg, keysAndValues)
 }
@@ -271,7 +271,7 @@ func (s *SugaredLogger) DPanicw(msg string, keysAndValues ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.DPanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	keysAndValues = append(keysAndValues, zap.String("caller", c.FileLine))
 	s.SugaredLogger.DPanicw(msg, keysAndValues)
 }
@@ -280,7 +280,7 @@ func (s *SugaredLogger) Panicw(msg string, keysAndValues ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.PanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	keysAndValues = append(keysAndValues, zap.String("caller", c.FileLine))
 	s.SugaredLogger.Panicw(msg, keysAndValues)
 }
@@ -289,7 +289,7 @@ func (s *SugaredLogger) Fatalw(msg string, keysAndValues ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.FatalLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	keysAndValues = append(keysAndValues, zap.String("caller", c.FileLine))
 	s.SugaredLogger.Fatalw(msg, keysAndValues)
 }
@@ -298,7 +298,7 @@ func (s *SugaredLogger) Debugln(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.DebugLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgsLn(args)
 	s.getLogger().Logger.Debug(msg, zap.String("caller", c.FileLine))
 }
@@ -307,7 +307,7 @@ func (s *SugaredLogger) Infoln(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.InfoLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgsLn(args)
 	s.getLogger().Logger.Info(msg, zap.String("caller", c.FileLine))
 }
@@ -316,7 +316,7 @@ func (s *SugaredLogger) Warnln(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.WarnLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgsLn(args)
 	s.getLogger().Logger.Warn(msg, zap.String("caller", c.FileLine))
 }
@@ -325,7 +325,7 @@ func (s *SugaredLogger) Errorln(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.ErrorLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgsLn(args)
 	s.getLogger().Logger.Error(msg, zap.String("caller", c.FileLine))
 }
@@ -334,7 +334,7 @@ func (s *SugaredLogger) DPanicln(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.DPanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgsLn(args)
 	s.getLogger().Logger.DPanic(msg, zap.String("caller", c.FileLine))
 }
@@ -343,7 +343,7 @@ func (s *SugaredLogger) Panicln(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.PanicLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgsLn(args)
 	s.getLogger().Logger.Panic(msg, zap.String("caller", c.FileLine))
 }
@@ -352,7 +352,7 @@ func (s *SugaredLogger) Fatalln(args ...interface{}) {
 	if !s.getLogger().getZapCore().Enabled(zap.FatalLevel) {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(s.getLogger().pathMode())
 	msg := getArgsLn(args)
 	s.getLogger().Logger.Fatal(msg, zap.String("caller", c.FileLine))
 }
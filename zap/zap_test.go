@@ -1,8 +1,11 @@
 package zap
 
 import (
+	"bytes"
 	"io"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -284,3 +287,41 @@ func BenchmarkZapCaller(b *testing.B) {
 		)
 	}
 }
+
+func TestPathMode(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	dir := file[:strings.LastIndexByte(file, '/')+1]
+	newLogger := func(w io.Writer, opts ...zap.Option) *Logger {
+		core := zapcore.NewCore(
+			zapcore.NewJSONEncoder(zap.NewProductionConfig().EncoderConfig),
+			zapcore.AddSync(w),
+			zapcore.InfoLevel,
+		)
+		return New(core, opts...)
+	}
+
+	w1, w2 := &bytes.Buffer{}, &bytes.Buffer{}
+	full := newLogger(w1, WithPathMode(errors.PathFull))
+	def := newLogger(w2)
+	full.Info("info msg")
+	def.Info("info msg")
+	if !strings.Contains(w1.String(), `"caller":"`+dir) {
+		t.Fatal(w1.String())
+	}
+	if !strings.Contains(w2.String(), `"caller":"zap/`) {
+		t.Fatal(w2.String())
+	}
+
+	// 派生的 logger 沿用设置
+	w1.Reset()
+	toLogger(full.With(zap.Int("k", 1))).Sugar().Infof("info %d", 1)
+	if !strings.Contains(w1.String(), `"caller":"`+dir) || !strings.Contains(w1.String(), `"k":1`) {
+		t.Fatal(w1.String())
+	}
+
+	w2.Reset()
+	def.WithOptions(WithPathMode(errors.PathFull)).Info("info msg")
+	if !strings.Contains(w2.String(), `"caller":"`+dir) {
+		t.Fatal(w2.String())
+	}
+}
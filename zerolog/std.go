@@ -117,7 +117,7 @@ func (e *Event) PrintWithPC(pc errors.PC, args ...interface{}) {
 		// e = e.Timestamp().Str(
 		e = e.Str(
 			zerolog.CallerFieldName,
-			pc.CallerFrame().FileLine,
+			pc.CallerFrameMode(e.pathMode()).FileLine,
 		)
 		e.Msg(fmt.Sprint(args...))
 	}
@@ -129,7 +129,7 @@ func (e *Event) PrintfWithPC(pc errors.PC, format string, args ...interface{}) {
 		// e = e.Timestamp().Str(
 		e = e.Str(
 			zerolog.CallerFieldName,
-			pc.CallerFrame().FileLine,
+			pc.CallerFrameMode(e.pathMode()).FileLine,
 		)
 		e.Msgf(format, args...)
 	}
@@ -238,7 +238,7 @@ func (l *Logger) Logf(level Level, format string, v ...interface{}) {
 	if level < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	(*zerolog.Logger)(l).WithLevel(zerolog.Level(level)).Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -283,7 +283,7 @@ func (l *Logger) LogAttrs(ctx context.Context, slevel slog.Level, attrs ...slog.
 	if level < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	e := (*zerolog.Logger)(l).WithLevel(zerolog.Level(level)).Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -295,7 +295,7 @@ func (l *Logger) LogfAttrs(ctx context.Context, slevel slog.Level, msg string, a
 	if level < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	e := (*zerolog.Logger)(l).WithLevel(zerolog.Level(level)).Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -307,7 +307,7 @@ func (l *Logger) Debugln(v ...interface{}) {
 	if DebugLevel < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	(*zerolog.Logger)(l).Debug().Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -317,7 +317,7 @@ func (l *Logger) Debugf(format string, v ...interface{}) {
 	if DebugLevel < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	(*zerolog.Logger)(l).Debug().Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -328,7 +328,7 @@ func (l *Logger) Infof(format string, v ...interface{}) {
 	if InfoLevel < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	(*zerolog.Logger)(l).Info().Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -338,7 +338,7 @@ func (l *Logger) Infoln(v ...interface{}) {
 	if InfoLevel < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	(*zerolog.Logger)(l).Info().Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -349,7 +349,7 @@ func (l *Logger) Warnf(format string, v ...interface{}) {
 	if WarnLevel < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	(*zerolog.Logger)(l).Warn().Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -359,7 +359,7 @@ func (l *Logger) Warnln(v ...interface{}) {
 	if WarnLevel < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	(*zerolog.Logger)(l).Warn().Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -370,7 +370,7 @@ func (l *Logger) Errorf(format string, v ...interface{}) {
 	if ErrorLevel < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	(*zerolog.Logger)(l).Error().Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -380,7 +380,7 @@ func (l *Logger) Errorln(v ...interface{}) {
 	if ErrorLevel < l.GetLevel() {
 		return
 	}
-	c := errors.GetPC().CallerFrame()
+	c := errors.GetPC().CallerFrameMode(l.pathMode())
 	(*zerolog.Logger)(l).Error().Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -692,7 +692,7 @@ func (ch callerHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
 	if ch.callerSkipFrameCount < 1 {
 		ch.callerSkipFrameCount = 2
 	}
-	cs := errors.CallersSkip(ch.callerSkipFrameCount - 1)
+	cs := errors.CallersSkipMode(ch.callerSkipFrameCount-1, toEvent(e).pathMode())
 	e.Str(
 		zerolog.CallerFieldName,
 		cs[0].FileLine, //zerolog.CallerMarshalFunc(0, cs[0].File, cs[0].Line),
@@ -704,7 +704,7 @@ type callerWith struct {
 }
 
 func (cw callerWith) Run(e *zerolog.Event, level zerolog.Level, msg string) {
-	c := errors.CallerFrame(cw.pc)
+	c := errors.CallerFrameMode(cw.pc, toEvent(e).pathMode())
 	e = e.Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -1087,6 +1087,49 @@ func (e *Event) CallerSkipFrame(skip int) *Event {
 	return toEvent(toZeroEvent(e).CallerSkipFrame(skip))
 }
 
+// pathModeHook 只用于记录 Logger 的路径格式, 见 Logger.WithPathMode
+type pathModeHook errors.PathMode
+
+func (pathModeHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {}
+
+// WithPathMode 返回输出 caller 时使用 mode 格式路径的子 Logger, 由它派生的 Logger、Event 同样有效;
+// 默认为 errors.PathDefault, 即跟随 errors.SetPathMode
+func (l Logger) WithPathMode(mode errors.PathMode) Logger {
+	return Logger(zerolog.Logger(l).Hook(pathModeHook(mode)))
+}
+
+func pathModeOf(hooks []zerolog.Hook) errors.PathMode {
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if m, ok := hooks[i].(pathModeHook); ok {
+			return errors.PathMode(m)
+		}
+	}
+	return errors.PathDefault
+}
+
+func (l *Logger) pathMode() errors.PathMode {
+	return pathModeOf(*(*[]zerolog.Hook)(unsafe.Pointer(uintptr(unsafe.Pointer(l)) + zeroLoggerHooksOffset)))
+}
+
+func (e *Event) pathMode() errors.PathMode {
+	if e == nil {
+		return errors.PathDefault
+	}
+	return pathModeOf(*(*[]zerolog.Hook)(unsafe.Pointer(uintptr(unsafe.Pointer(e)) + zeroEventHooksOffset)))
+}
+
+// 使用heck的方式获取 zerolog.Logger.hooks 和 zerolog.Event.ch
+var zeroLoggerHooksOffset, zeroEventHooksOffset = hooksOffset(zerolog.Logger{}, "hooks"), hooksOffset(zerolog.Event{}, "ch")
+
+func hooksOffset(v interface{}, name string) uintptr {
+	typ := reflect.TypeOf(v)
+	field, ok := typ.FieldByName(name)
+	if !ok || field.Type != reflect.TypeOf([]zerolog.Hook(nil)) {
+		panic(typ.String() + "." + name + " not exist")
+	}
+	return field.Offset
+}
+
 // 使用heck的方式获取 zerolog.Event.skipFrame
 var zeroEventSkipFrameOffset = func() uintptr {
 	typ := reflect.TypeOf(zerolog.Event{})
@@ -1113,7 +1156,7 @@ func (e *Event) Caller(skip ...int) *Event {
 		levelSkip += skip[0]
 	}
 	if levelSkip <= 2 {
-		c := errors.GetPC().CallerFrame()
+		c := errors.GetPC().CallerFrameMode(e.pathMode())
 		e = e.Str(
 			zerolog.CallerFieldName,
 			c.FileLine, // zerolog.CallerMarshalFunc(0, c.File, c.Line),
@@ -1121,7 +1164,7 @@ func (e *Event) Caller(skip ...int) *Event {
 		return e
 	}
 	if levelSkip-2 <= errors.DefaultDepth {
-		cs := errors.CallersSkip(levelSkip - 2)
+		cs := errors.CallersSkipMode(levelSkip-2, e.pathMode())
 		e = e.Str(
 			zerolog.CallerFieldName,
 			cs[0].FileLine, //zerolog.CallerMarshalFunc(0, cs[0].File, cs[0].Line),
@@ -1136,7 +1179,7 @@ func (e *Event) WithCaller(pc uintptr) *Event {
 		return e
 	}
 
-	c := errors.CallerFrame(pc)
+	c := errors.CallerFrameMode(pc, e.pathMode())
 	e = e.Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -1164,7 +1207,7 @@ func (l *Logger) WithCaller(ctx context.Context, pc uintptr, slevel slog.Level,
 	if level < l.GetLevel() {
 		return
 	}
-	c := errors.CallerFrame(pc)
+	c := errors.CallerFrameMode(pc, l.pathMode())
 	e := (*zerolog.Logger)(l).WithLevel(zerolog.Level(level)).Str(
 		zerolog.CallerFieldName,
 		c.FileLine,
@@ -1,9 +1,12 @@
 package zerolog
 
 import (
+	"bytes"
 	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -428,3 +431,28 @@ func BenchmarkZeroCaller(b *testing.B) {
 			Msg("some log messages")
 	}
 }
+
+func TestPathMode(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	dir := file[:strings.LastIndexByte(file, '/')+1]
+
+	w1, w2 := &bytes.Buffer{}, &bytes.Buffer{}
+	full := New(w1).WithPathMode(errors.PathFull)
+	def := New(w2)
+	full.Infof("info %d", 1)
+	def.Infof("info %d", 1)
+	if !strings.Contains(w1.String(), `"caller":"`+dir) {
+		t.Fatal(w1.String())
+	}
+	if !strings.Contains(w2.String(), `"caller":"zerolog/`) {
+		t.Fatal(w2.String())
+	}
+
+	// 派生的 Logger 和 Event 沿用设置
+	w1.Reset()
+	child := full.With().Str("k", "v").Logger()
+	child.Info().Caller().Msg("info")
+	if !strings.Contains(w1.String(), `"caller":"`+dir) || !strings.Contains(w1.String(), `"k":"v"`) {
+		t.Fatal(w1.String())
+	}
+}